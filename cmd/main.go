@@ -2,11 +2,9 @@ package main
 
 import (
 	"fmt"
-	"log"
 	"os"
 
 	"restic-backup-checker/internal/cli"
-	"restic-backup-checker/internal/config"
 	"restic-backup-checker/internal/logger"
 )
 
@@ -17,16 +15,10 @@ func main() {
 	// Initialize logger
 	logger.Init()
 
-	// Initialize configuration
-	cfg, err := config.Load()
-	if err != nil {
-		log.Fatalf("Failed to load configuration: %v", err)
-	}
-
-	// Create and execute CLI
-	rootCmd := cli.NewRootCommand(cfg, version)
-	if err := rootCmd.Execute(); err != nil {
+	// Create and execute CLI; the configuration is loaded once flags are parsed
+	rootCmd := cli.NewRootCommand(version)
+	if cmd, err := rootCmd.ExecuteC(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		os.Exit(cli.ExitCode(cmd))
 	}
 }
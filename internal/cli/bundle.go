@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"restic-backup-checker/internal/config"
+	"restic-backup-checker/internal/logger"
+
+	"github.com/spf13/cobra"
+)
+
+// newConfigExportCommand creates the config export command
+func newConfigExportCommand(cfg *config.Config) *cobra.Command {
+	var noTokens bool
+	cmd := &cobra.Command{
+		Use:   "export <file>",
+		Short: "Export the configuration for another host",
+		Long: `Write all settings, including the OneDrive tokens, monitored folders, notification channels and
+routing and escalation policies, to a bundle encrypted with a passphrase. The passphrase is prompted
+for, or read from RBC_BUNDLE_PASSPHRASE. Import the bundle on the new host with 'config import'.
+
+Template files are referenced by path and not included.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			data, err := cfg.Export("", !noTokens)
+			if err != nil {
+				return fmt.Errorf("failed to export configuration: %w", err)
+			}
+			if err := os.WriteFile(args[0], data, 0600); err != nil {
+				return fmt.Errorf("failed to write bundle: %w", err)
+			}
+			if noTokens {
+				logger.Info("Configuration exported to %s without OneDrive tokens", args[0])
+			} else {
+				logger.Info("Configuration exported to %s", args[0])
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&noTokens, "no-tokens", false, "leave out the OneDrive tokens; run 'login' on the new host")
+	return cmd
+}
+
+// newConfigImportCommand creates the config import command
+func newConfigImportCommand(cfg *config.Config) *cobra.Command {
+	var noTokens bool
+	cmd := &cobra.Command{
+		Use:   "import <file>",
+		Short: "Import a configuration exported on another host",
+		Long: `Replace all settings with those of a bundle written by 'config export' and save them encrypted
+with the local key source. The passphrase is prompted for, or read from RBC_BUNDLE_PASSPHRASE.
+The previous configuration is kept as config.enc.bak.
+
+The local OneDrive tokens are kept if the bundle has none or --no-tokens is given.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			data, err := os.ReadFile(args[0])
+			if err != nil {
+				return fmt.Errorf("failed to read bundle: %w", err)
+			}
+			if err := cfg.Import(data, "", !noTokens); err != nil {
+				return fmt.Errorf("failed to import configuration: %w", err)
+			}
+			if err := cfg.Save(); err != nil {
+				return fmt.Errorf("failed to save configuration: %w", err)
+			}
+			logger.Info("Configuration imported from %s, key source: %s", args[0], describeKey(cfg))
+
+			for _, p := range configProblems(cfg) {
+				logger.Warn("Imported configuration: %s", p)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&noTokens, "no-tokens", false, "keep the local OneDrive tokens instead of those in the bundle")
+	return cmd
+}
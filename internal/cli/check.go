@@ -0,0 +1,255 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"restic-backup-checker/internal/config"
+	"restic-backup-checker/internal/logger"
+	"restic-backup-checker/internal/monitor"
+	"restic-backup-checker/internal/state"
+	"restic-backup-checker/internal/templates"
+
+	"github.com/spf13/cobra"
+)
+
+// Exit codes following the monitoring plugins convention
+const (
+	exitOK       = 0
+	exitWarning  = 1
+	exitCritical = 2
+	exitUnknown  = 3
+)
+
+// exitNames names the exit codes in plugin output
+var exitNames = map[int]string{
+	exitOK:       "OK",
+	exitWarning:  "WARNING",
+	exitCritical: "CRITICAL",
+	exitUnknown:  "UNKNOWN",
+}
+
+// exitRank orders exit codes by how much attention they need
+var exitRank = map[int]int{exitOK: 0, exitUnknown: 1, exitWarning: 2, exitCritical: 3}
+
+// checkThresholds holds the snapshot ages at which a client is a warning or critical
+type checkThresholds struct {
+	Warning  time.Duration
+	Critical time.Duration
+}
+
+// checkReport is the JSON output of the check command
+type checkReport struct {
+	Status   string `json:"status"` // OK, WARNING, CRITICAL or UNKNOWN
+	ExitCode int    `json:"exit_code"`
+	Error    string `json:"error,omitempty"`
+	*monitor.CheckResult
+	States map[string]string `json:"states"` // <monitor path>/<client> -> OK, WARNING, CRITICAL or UNKNOWN
+}
+
+// newCheckCommand creates the check command
+func newCheckCommand(cfg *config.Config) *cobra.Command {
+	var output string
+	var noNotify bool
+	var clients []string
+	thresholds := checkThresholds{Warning: 24 * time.Hour, Critical: 48 * time.Hour}
+
+	checkCmd := &cobra.Command{
+		Use:   "check",
+		Short: "Manually check backup status",
+		Long: `Manually check if backups are up to date and send notifications if needed.
+
+Exits 0 (OK), 1 (WARNING), 2 (CRITICAL) or 3 (UNKNOWN) like a monitoring plugin:
+clients whose newest snapshot is older than --warning-age or --critical-age, or that
+have no snapshots, are a warning or critical; clients that could not be checked are unknown.`,
+		Args: cobra.NoArgs,
+		// Invalid flags and configuration are UNKNOWN, not WARNING
+		Annotations: map[string]string{errorExitCode: strconv.Itoa(exitUnknown)},
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			switch output {
+			case "table", "json", "nagios":
+			default:
+				return fmt.Errorf("invalid output format %q, expected table, json or nagios", output)
+			}
+			if thresholds.Warning > thresholds.Critical {
+				return fmt.Errorf("--warning-age %s is greater than --critical-age %s", thresholds.Warning, thresholds.Critical)
+			}
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			result := &monitor.CheckResult{Clients: []templates.Client{}}
+			var err error
+			if cfg.IsConfigured() {
+				m := monitor.New(cfg)
+				result, err = m.Check(monitor.CheckOptions{Notify: !noNotify, Clients: clients})
+			} else {
+				err = errors.New("configuration not found, run 'restic-backup-checker setup' first")
+			}
+			if err != nil {
+				logger.Error("Failed to check backups: %v", err)
+			}
+
+			code, states := evaluateCheck(result, thresholds)
+			switch output {
+			case "json":
+				printCheckJSON(result, err, code, states)
+			case "nagios":
+				printCheckNagios(result, err, code, states, thresholds)
+			default:
+				printCheckTable(result, err, code, states)
+			}
+			os.Exit(code)
+		},
+	}
+
+	checkCmd.Flags().StringVarP(&output, "output", "o", "table", "output format: table, json or nagios")
+	checkCmd.Flags().BoolVar(&noNotify, "no-notify", false, "only report; send no notifications and leave the monitoring state untouched")
+	checkCmd.Flags().StringSliceVar(&clients, "client", nil, "check only clients matching this name pattern (repeatable)")
+	checkCmd.Flags().DurationVar(&thresholds.Warning, "warning-age", thresholds.Warning, "snapshot age from which a client is a warning")
+	checkCmd.Flags().DurationVar(&thresholds.Critical, "critical-age", thresholds.Critical, "snapshot age from which a client is critical")
+
+	return checkCmd
+}
+
+// evaluateCheck returns the exit code of a check and the plugin state of
+// every client, keyed by state.ClientKey as client names are only unique
+// within a monitored path
+func evaluateCheck(result *monitor.CheckResult, t checkThresholds) (int, map[string]string) {
+	states := make(map[string]string)
+	if len(result.Clients) == 0 {
+		return exitUnknown, states
+	}
+
+	code := exitOK
+	for _, c := range result.Clients {
+		state := clientExitCode(c, t)
+		states[checkKey(c)] = exitNames[state]
+		if exitRank[state] > exitRank[code] {
+			code = state
+		}
+	}
+	return code, states
+}
+
+// checkKey returns the key of a client in the plugin states
+func checkKey(c templates.Client) string {
+	return state.ClientKey(c.MonitorPath, c.Name)
+}
+
+// clientExitCode returns the plugin state of a single client
+func clientExitCode(c templates.Client, t checkThresholds) int {
+	switch c.Status {
+	case templates.StatusCheckError, templates.StatusAuthError:
+		return exitUnknown
+	case templates.StatusNoSnapshots, templates.StatusNotARepo:
+		return exitCritical
+	}
+
+	switch {
+	case c.LastBackup.IsZero() || c.Age >= t.Critical:
+		return exitCritical
+	case c.Age >= t.Warning:
+		return exitWarning
+	default:
+		return exitOK
+	}
+}
+
+// printCheckJSON prints the check result as JSON
+func printCheckJSON(result *monitor.CheckResult, err error, code int, states map[string]string) {
+	report := checkReport{
+		Status:      exitNames[code],
+		ExitCode:    code,
+		CheckResult: result,
+		States:      states,
+	}
+	if err != nil {
+		report.Error = err.Error()
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		logger.Error("Failed to write JSON output: %v", err)
+	}
+}
+
+// printCheckTable prints the check result as a table
+func printCheckTable(result *monitor.CheckResult, err error, code int, states map[string]string) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CLIENT\tSTATUS\tSTATE\tLAST BACKUP\tAGE\tFILES (24H)")
+	for _, c := range result.Clients {
+		age := "-"
+		if !c.LastBackup.IsZero() {
+			age = templates.FormatAge(c.Age)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\n", c.Name, c.Status, states[checkKey(c)], templates.FormatTime(c.LastBackup), age, c.FileCount)
+	}
+	w.Flush()
+
+	fmt.Printf("\n%s: %d of %d clients OK\n", exitNames[code], result.Run.Successful, result.Run.Total)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+	}
+}
+
+// printCheckNagios prints the check result in the monitoring plugin format:
+// a status line with performance data, followed by one line per problem
+func printCheckNagios(result *monitor.CheckResult, err error, code int, states map[string]string, t checkThresholds) {
+	var summary string
+	switch {
+	case err != nil && len(result.Clients) == 0:
+		summary = err.Error()
+	case len(result.Clients) == 0:
+		summary = "no clients checked"
+	default:
+		summary = fmt.Sprintf("%d of %d clients OK", result.Run.Successful, result.Run.Total)
+	}
+
+	var perfdata []string
+	var details []string
+	for _, c := range result.Clients {
+		label := perfLabel(perfName(c))
+		if !c.LastBackup.IsZero() {
+			perfdata = append(perfdata, fmt.Sprintf("'%s_age'=%.0fs;%.0f;%.0f;0", label, c.Age.Seconds(), t.Warning.Seconds(), t.Critical.Seconds()))
+		}
+		perfdata = append(perfdata, fmt.Sprintf("'%s_files'=%d;;;0", label, c.FileCount))
+
+		if states[checkKey(c)] != exitNames[exitOK] {
+			detail := fmt.Sprintf("%s: %s %s (%s)", states[checkKey(c)], c.Name, c.Status, templates.Describe(c.Status))
+			if !c.LastBackup.IsZero() {
+				detail += ", last backup " + templates.FormatAge(c.Age) + " ago"
+			}
+			if c.Error != "" {
+				detail += ": " + c.Error
+			}
+			details = append(details, detail)
+		}
+	}
+
+	fmt.Printf("BACKUP %s - %s", exitNames[code], summary)
+	if len(perfdata) > 0 {
+		fmt.Printf(" | %s", strings.Join(perfdata, " "))
+	}
+	fmt.Println()
+	for _, detail := range details {
+		fmt.Println(detail)
+	}
+}
+
+// perfName returns the perfdata name of a client: the path of its monitored
+// folder and its name, since client names are only unique within a folder
+func perfName(c templates.Client) string {
+	return strings.TrimPrefix(c.MonitorName, "/") + "/" + c.Name
+}
+
+// perfLabel makes a client name safe for use as a perfdata label
+func perfLabel(name string) string {
+	return strings.NewReplacer("'", "_", "=", "_", " ", "_").Replace(name)
+}
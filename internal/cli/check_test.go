@@ -0,0 +1,147 @@
+package cli
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"restic-backup-checker/internal/monitor"
+	"restic-backup-checker/internal/templates"
+)
+
+var testThresholds = checkThresholds{Warning: 24 * time.Hour, Critical: 48 * time.Hour}
+
+// checkedClient returns a checked client whose newest snapshot is age old
+func checkedClient(monitorPath, name, status string, age time.Duration) templates.Client {
+	c := templates.Client{Name: name, MonitorPath: monitorPath, Status: status}
+	if age > 0 {
+		c.LastBackup = time.Now().Add(-age)
+		c.Age = age
+	}
+	return c
+}
+
+func TestClientExitCode(t *testing.T) {
+	tests := []struct {
+		name   string
+		client templates.Client
+		want   int
+	}{
+		{"fresh", checkedClient("F", "a", templates.StatusOK, time.Hour), exitOK},
+		{"warning age", checkedClient("F", "a", templates.StatusStale, 30*time.Hour), exitWarning},
+		{"at warning age", checkedClient("F", "a", templates.StatusStale, 24*time.Hour), exitWarning},
+		{"critical age", checkedClient("F", "a", templates.StatusStale, 48*time.Hour), exitCritical},
+		{"never backed up", checkedClient("F", "a", templates.StatusStale, 0), exitCritical},
+		{"no snapshots", checkedClient("F", "a", templates.StatusNoSnapshots, time.Hour), exitCritical},
+		{"not a repository", checkedClient("F", "a", templates.StatusNotARepo, 0), exitCritical},
+		{"check error", checkedClient("F", "a", templates.StatusCheckError, 100*time.Hour), exitUnknown},
+		{"auth error", checkedClient("F", "a", templates.StatusAuthError, 0), exitUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := clientExitCode(tt.client, testThresholds); got != tt.want {
+				t.Errorf("clientExitCode() = %s, want %s", exitNames[got], exitNames[tt.want])
+			}
+		})
+	}
+}
+
+func TestEvaluateCheck(t *testing.T) {
+	tests := []struct {
+		name       string
+		clients    []templates.Client
+		wantCode   int
+		wantStates map[string]string
+	}{
+		{
+			name:       "no clients",
+			wantCode:   exitUnknown,
+			wantStates: map[string]string{},
+		},
+		{
+			name: "all fresh",
+			clients: []templates.Client{
+				checkedClient("F1", "web01", templates.StatusOK, time.Hour),
+				checkedClient("F1", "db01", templates.StatusOK, 2*time.Hour),
+			},
+			wantCode:   exitOK,
+			wantStates: map[string]string{"F1/web01": "OK", "F1/db01": "OK"},
+		},
+		{
+			name: "warning wins over unknown",
+			clients: []templates.Client{
+				checkedClient("F1", "web01", templates.StatusCheckError, 0),
+				checkedClient("F1", "db01", templates.StatusStale, 30*time.Hour),
+			},
+			wantCode:   exitWarning,
+			wantStates: map[string]string{"F1/web01": "UNKNOWN", "F1/db01": "WARNING"},
+		},
+		{
+			name: "same name in two monitored paths",
+			clients: []templates.Client{
+				checkedClient("F1", "web01", templates.StatusOK, time.Hour),
+				checkedClient("F2", "web01", templates.StatusNoSnapshots, 0),
+			},
+			wantCode:   exitCritical,
+			wantStates: map[string]string{"F1/web01": "OK", "F2/web01": "CRITICAL"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, states := evaluateCheck(&monitor.CheckResult{Clients: tt.clients}, testThresholds)
+			if code != tt.wantCode {
+				t.Errorf("evaluateCheck() code = %s, want %s", exitNames[code], exitNames[tt.wantCode])
+			}
+			if !reflect.DeepEqual(states, tt.wantStates) {
+				t.Errorf("evaluateCheck() states = %v, want %v", states, tt.wantStates)
+			}
+		})
+	}
+}
+
+func TestClientAgeInJSON(t *testing.T) {
+	c := checkedClient("F1", "web01", templates.StatusOK, time.Hour)
+	c.AgeSeconds = c.Age.Seconds()
+	data, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"age_seconds":3600`) || strings.Contains(string(data), `"age":`) {
+		t.Errorf("age not reported in seconds: %s", data)
+	}
+}
+
+func TestPerfLabel(t *testing.T) {
+	if got := perfLabel("acme web='01'"); got != "acme_web__01_" {
+		t.Errorf("perfLabel() = %q", got)
+	}
+}
+
+func TestPerfName(t *testing.T) {
+	a := templates.Client{Name: "web01", MonitorPath: "F1", MonitorName: "/Backups/site-a"}
+	b := templates.Client{Name: "web01", MonitorPath: "F2", MonitorName: "/Backups/site-b"}
+	if perfName(a) == perfName(b) {
+		t.Errorf("perfName() = %q for clients in two monitored folders", perfName(a))
+	}
+	if got := perfLabel(perfName(a)); got != "Backups/site-a/web01" {
+		t.Errorf("perfLabel(perfName()) = %q", got)
+	}
+}
+
+func TestCheckErrorExitCode(t *testing.T) {
+	root := NewRootCommand("test")
+	check, _, err := root.Find([]string{"check"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ExitCode(check); got != exitUnknown {
+		t.Errorf("ExitCode(check) = %d, want %d", got, exitUnknown)
+	}
+	if got := ExitCode(root); got != 1 {
+		t.Errorf("ExitCode(root) = %d, want 1", got)
+	}
+}
@@ -4,56 +4,175 @@ import (
 	"bufio"
 	"fmt"
 	"os"
+	"path"
 	"strconv"
 	"strings"
+	"time"
 
 	"restic-backup-checker/internal/config"
 	"restic-backup-checker/internal/logger"
 	"restic-backup-checker/internal/monitor"
 	"restic-backup-checker/internal/onedrive"
+	"restic-backup-checker/internal/opsgenie"
+	"restic-backup-checker/internal/pagerduty"
 	"restic-backup-checker/internal/telegram"
+	"restic-backup-checker/internal/templates"
 
 	"github.com/spf13/cobra"
 )
 
-// NewRootCommand creates the root command
-func NewRootCommand(cfg *config.Config, version string) *cobra.Command {
+// noConfig marks commands that run without loading the configuration
+const noConfig = "no-config"
+
+// errorExitCode holds the exit code of a command that fails with an error,
+// e.g. on invalid flags or configuration; 1 if unset
+const errorExitCode = "error-exit-code"
+
+// ExitCode returns the exit code for an error returned by cmd
+func ExitCode(cmd *cobra.Command) int {
+	if cmd != nil {
+		if code, err := strconv.Atoi(cmd.Annotations[errorExitCode]); err == nil {
+			return code
+		}
+	}
+	return 1
+}
+
+// NewRootCommand creates the root command. The configuration is loaded
+// once flags are parsed, so the key source can be chosen on the command line.
+func NewRootCommand(version string) *cobra.Command {
+	cfg := &config.Config{}
+	var loadOpts config.LoadOptions
+	var allProfiles bool
 	var rootCmd = &cobra.Command{
 		Use:   "restic-backup-checker",
 		Short: "A tool to check restic backup status on OneDrive",
 		Long:  `Restic Backup Checker monitors OneDrive folders for daily restic backup snapshots and sends notifications via Telegram.`,
-		Run: func(cmd *cobra.Command, args []string) {
-			if !cfg.IsConfigured() {
-				logger.Info("Configuration not found. Please run 'restic-backup-checker setup' first.")
-				return
-			}
-
-			// Start monitoring
-			monitor := monitor.New(cfg)
-			if err := monitor.Start(); err != nil {
-				logger.Error("Failed to start monitoring: %v", err)
-				return
+		// main reports errors
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if allProfiles {
+				return runProfiles(loadOpts)
 			}
+			return runMonitor(cfg, logger.With())
 		},
 	}
 
+	rootCmd.Flags().BoolVar(&allProfiles, "all-profiles", false, "monitor all profiles at once")
+	rootCmd.PersistentFlags().StringVar(&loadOpts.Profile, "profile", "", "configuration profile to use (default from RBC_PROFILE, else the default profile)")
+	rootCmd.PersistentFlags().StringVar(&loadOpts.File, "config", "", "read settings from this YAML or TOML file; the encrypted store then only keeps OAuth tokens")
+	rootCmd.MarkFlagsMutuallyExclusive("all-profiles", "profile")
+	rootCmd.MarkFlagsMutuallyExclusive("all-profiles", "config")
+	keyOpts := &loadOpts.Key
+	rootCmd.PersistentFlags().StringVar(&keyOpts.Source, "key-source", "", "config encryption key source: passphrase, keyfile or systemd (default from the config file)")
+	rootCmd.PersistentFlags().StringVar(&keyOpts.KeyFile, "key-file", "", "key file for the keyfile key source (default config.key next to the config file)")
+	rootCmd.PersistentFlags().StringVar(&keyOpts.Credential, "key-credential", "", "systemd credential name for the systemd key source (default config-key)")
+
+	logging := config.LoggingConfig{MaxSizeMB: 10, MaxBackups: 5}
+	rootCmd.PersistentFlags().StringVar(&logging.Level, "log-level", "", "log level: debug, info, warn or error (default info)")
+	rootCmd.PersistentFlags().StringVar(&logging.Format, "log-format", "", "log format: text or json (default text)")
+	rootCmd.PersistentFlags().StringVar(&logging.File, "log-file", "", "write logs to this file instead of stderr")
+	rootCmd.PersistentFlags().IntVar(&logging.MaxSizeMB, "log-max-size", logging.MaxSizeMB, "rotate the log file at this size in MB")
+	rootCmd.PersistentFlags().IntVar(&logging.MaxAgeDays, "log-max-age", logging.MaxAgeDays, "delete rotated log files older than this many days, 0 keeps them")
+	rootCmd.PersistentFlags().IntVar(&logging.MaxBackups, "log-max-backups", logging.MaxBackups, "number of rotated log files kept, 0 keeps all")
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		// Flags are valid at this point, failures below are not usage errors
+		cmd.SilenceUsage = true
+		if !needsConfig(cmd) {
+			return nil
+		}
+		if allProfiles {
+			// Every profile is loaded by runProfiles, log as the flags say
+			return logger.Configure(loggerOptions(cmd, config.LoggingConfig{}, logging))
+		}
+
+		loaded, err := config.Load(loadOpts)
+		if err != nil {
+			return fmt.Errorf("failed to load configuration: %w", err)
+		}
+		*cfg = *loaded
+
+		if err := logger.Configure(loggerOptions(cmd, cfg.Logging, logging)); err != nil {
+			return err
+		}
+		if cfg.KeySource() == config.KeySourceLegacy {
+			logger.Warn("The configuration is encrypted with a key derived from the hostname and user name, run 'restic-backup-checker config rekey' to switch to a passphrase or key file")
+		}
+		return nil
+	}
 	// Add subcommands
 	rootCmd.AddCommand(newLoginCommand(cfg))
 	rootCmd.AddCommand(newLogoutCommand(cfg))
 	rootCmd.AddCommand(newSetupCommand(cfg))
 	rootCmd.AddCommand(newCheckCommand(cfg))
 	rootCmd.AddCommand(newConfigCommand(cfg))
+	rootCmd.AddCommand(newTemplatesCommand(cfg))
+	rootCmd.AddCommand(newRoutesCommand(cfg))
+	rootCmd.AddCommand(newEscalationCommand(cfg))
+	rootCmd.AddCommand(newPathsCommand(cfg))
+	rootCmd.AddCommand(newProfileCommand())
 	rootCmd.AddCommand(newVersionCommand(version))
 
 	return rootCmd
 }
 
+// needsConfig reports whether a command needs the configuration loaded
+func needsConfig(cmd *cobra.Command) bool {
+	for c := cmd; c != nil; c = c.Parent() {
+		if c.Annotations[noConfig] != "" || c.Name() == "help" || c.Name() == "completion" {
+			return false
+		}
+	}
+	return true
+}
+
+// loggerOptions returns the logging settings of the configuration,
+// overridden by the logging flags given on the command line
+func loggerOptions(cmd *cobra.Command, logging, flags config.LoggingConfig) logger.Options {
+	if logging.MaxSizeMB == 0 {
+		logging.MaxSizeMB = flags.MaxSizeMB
+	}
+	if logging.MaxBackups == 0 {
+		logging.MaxBackups = flags.MaxBackups
+	}
+
+	set := cmd.Flags().Changed
+	if set("log-level") {
+		logging.Level = flags.Level
+	}
+	if set("log-format") {
+		logging.Format = flags.Format
+	}
+	if set("log-file") {
+		logging.File = flags.File
+	}
+	if set("log-max-size") {
+		logging.MaxSizeMB = flags.MaxSizeMB
+	}
+	if set("log-max-age") {
+		logging.MaxAgeDays = flags.MaxAgeDays
+	}
+	if set("log-max-backups") {
+		logging.MaxBackups = flags.MaxBackups
+	}
+
+	return logger.Options{
+		Level:      logging.Level,
+		Format:     logging.Format,
+		File:       logging.File,
+		MaxSizeMB:  logging.MaxSizeMB,
+		MaxAgeDays: logging.MaxAgeDays,
+		MaxBackups: logging.MaxBackups,
+	}
+}
+
 // newVersionCommand creates the version command
 func newVersionCommand(version string) *cobra.Command {
 	return &cobra.Command{
-		Use:   "version",
-		Short: "Show version information",
-		Long:  `Display the current version of restic-backup-checker.`,
+		Use:         "version",
+		Short:       "Show version information",
+		Long:        `Display the current version of restic-backup-checker.`,
+		Annotations: map[string]string{noConfig: "true"},
 		Run: func(cmd *cobra.Command, args []string) {
 			fmt.Printf("restic-backup-checker version %s\n", version)
 		},
@@ -67,6 +186,11 @@ func newSetupCommand(cfg *config.Config) *cobra.Command {
 		Short: "Set up folder monitoring and Telegram notifications",
 		Long:  `Interactive setup for folder monitoring and Telegram notifications. Run 'restic-backup-checker login' first to authenticate with OneDrive.`,
 		Run: func(cmd *cobra.Command, args []string) {
+			if cfg.SettingsFile() != "" {
+				logger.Error("Settings are read from %s, edit that file instead of running setup", cfg.SettingsFile())
+				return
+			}
+
 			if err := setupOneDrive(cfg); err != nil {
 				logger.Error("Failed to setup OneDrive: %v", err)
 				return
@@ -77,40 +201,27 @@ func newSetupCommand(cfg *config.Config) *cobra.Command {
 				return
 			}
 
-			if err := setupMonitoring(cfg); err != nil {
-				logger.Error("Failed to setup monitoring: %v", err)
+			if err := setupEmail(cfg); err != nil {
+				logger.Error("Failed to setup email: %v", err)
 				return
 			}
 
-			if err := cfg.Save(); err != nil {
-				logger.Error("Failed to save configuration: %v", err)
+			if err := setupIncidents(cfg); err != nil {
+				logger.Error("Failed to setup incident channels: %v", err)
 				return
 			}
 
-			logger.Info("Setup completed successfully!")
-		},
-	}
-}
-
-// newCheckCommand creates the check command for manual backup verification
-func newCheckCommand(cfg *config.Config) *cobra.Command {
-	return &cobra.Command{
-		Use:   "check",
-		Short: "Manually check backup status",
-		Long:  `Manually check if backups are up to date and send notifications if needed.`,
-		Run: func(cmd *cobra.Command, args []string) {
-			if !cfg.IsConfigured() {
-				logger.Error("Configuration not found. Please run 'restic-backup-checker setup' first.")
+			if err := setupMonitoring(cfg); err != nil {
+				logger.Error("Failed to setup monitoring: %v", err)
 				return
 			}
 
-			monitor := monitor.New(cfg)
-			if err := monitor.CheckOnce(); err != nil {
-				logger.Error("Failed to check backups: %v", err)
+			if err := cfg.Save(); err != nil {
+				logger.Error("Failed to save configuration: %v", err)
 				return
 			}
 
-			logger.Info("Backup check completed.")
+			logger.Info("Setup completed successfully!")
 		},
 	}
 }
@@ -136,7 +247,7 @@ func newConfigCommand(cfg *config.Config) *cobra.Command {
 		Short: "Reset configuration",
 		Run: func(cmd *cobra.Command, args []string) {
 			if confirmReset() {
-				*cfg = config.Config{}
+				cfg.Reset()
 				if err := cfg.Save(); err != nil {
 					logger.Error("Failed to reset configuration: %v", err)
 					return
@@ -146,9 +257,150 @@ func newConfigCommand(cfg *config.Config) *cobra.Command {
 		},
 	})
 
+	configCmd.AddCommand(newConfigGetCommand(cfg))
+	configCmd.AddCommand(newConfigSetCommand(cfg))
+	configCmd.AddCommand(newConfigUnsetCommand(cfg))
+	configCmd.AddCommand(newConfigKeysCommand())
+	configCmd.AddCommand(newRekeyCommand(cfg))
+	configCmd.AddCommand(newConfigValidateCommand(cfg))
+	configCmd.AddCommand(newConfigExportCommand(cfg))
+	configCmd.AddCommand(newConfigImportCommand(cfg))
+
 	return configCmd
 }
 
+// newRekeyCommand creates the config rekey command
+func newRekeyCommand(cfg *config.Config) *cobra.Command {
+	var opts config.KeyOptions
+
+	rekeyCmd := &cobra.Command{
+		Use:   "rekey passphrase|keyfile|systemd",
+		Short: "Re-encrypt the configuration with a new key",
+		Long: `Re-encrypt the configuration file with a key from a new source and a new random salt.
+
+passphrase  prompts for a new passphrase, or reads it from RBC_NEW_CONFIG_PASSPHRASE
+keyfile     uses --new-key-file, generating a random key if the file does not exist
+systemd     reads the systemd credential --new-credential from $CREDENTIALS_DIRECTORY
+
+Files encrypted with the legacy hostname and user key are migrated the same way.`,
+		Args:      cobra.ExactArgs(1),
+		ValidArgs: []string{config.KeySourcePassphrase, config.KeySourceKeyFile, config.KeySourceSystemd},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.Source = args[0]
+			opts.Passphrase = os.Getenv("RBC_NEW_CONFIG_PASSPHRASE")
+			if err := cfg.Rekey(opts); err != nil {
+				return fmt.Errorf("failed to rekey configuration: %w", err)
+			}
+			logger.Info("Configuration re-encrypted, key source: %s", describeKey(cfg))
+			return nil
+		},
+	}
+
+	rekeyCmd.Flags().StringVar(&opts.KeyFile, "new-key-file", "", "key file for the keyfile key source (default config.key next to the config file)")
+	rekeyCmd.Flags().StringVar(&opts.Credential, "new-credential", "", "systemd credential name for the systemd key source (default config-key)")
+
+	return rekeyCmd
+}
+
+// newTemplatesCommand creates the templates command
+func newTemplatesCommand(cfg *config.Config) *cobra.Command {
+	templatesCmd := &cobra.Command{
+		Use:   "templates",
+		Short: "Manage notification templates",
+		Long:  `List, configure and test-render the text/template templates used for notifications.`,
+	}
+
+	templatesCmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List templates and their source",
+		Run: func(cmd *cobra.Command, args []string) {
+			for _, name := range templates.Default().Names() {
+				source := "built-in"
+				if path, ok := cfg.Templates[name]; ok {
+					source = path
+				}
+				fmt.Printf("%-20s %s\n", name, source)
+			}
+		},
+	})
+
+	templatesCmd.AddCommand(&cobra.Command{
+		Use:   "set <type.channel> <file>",
+		Short: "Use a template file for a notification type and channel",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			files := map[string]string{args[0]: args[1]}
+			if _, err := templates.New(files); err != nil {
+				return fmt.Errorf("invalid template: %w", err)
+			}
+
+			if cfg.Templates == nil {
+				cfg.Templates = make(map[string]string)
+			}
+			cfg.Templates[args[0]] = args[1]
+			if err := cfg.Save(); err != nil {
+				return fmt.Errorf("failed to save configuration: %w", err)
+			}
+			logger.Info("Template %s set to %s", args[0], args[1])
+			return nil
+		},
+	})
+
+	templatesCmd.AddCommand(&cobra.Command{
+		Use:   "unset <type.channel>",
+		Short: "Revert a notification type and channel to the built-in template",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			delete(cfg.Templates, args[0])
+			if err := cfg.Save(); err != nil {
+				return fmt.Errorf("failed to save configuration: %w", err)
+			}
+			logger.Info("Template %s reverted to built-in", args[0])
+			return nil
+		},
+	})
+
+	templatesCmd.AddCommand(&cobra.Command{
+		Use:   "test [type.channel...]",
+		Short: "Render templates with sample data",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := testTemplates(cfg, args); err != nil {
+				return fmt.Errorf("template test failed: %w", err)
+			}
+			return nil
+		},
+	})
+
+	return templatesCmd
+}
+
+// testTemplates renders the configured templates with sample data
+func testTemplates(cfg *config.Config, names []string) error {
+	renderer, err := templates.New(cfg.Templates)
+	if err != nil {
+		return err
+	}
+
+	if len(names) == 0 {
+		names = renderer.Names()
+	}
+
+	for _, name := range names {
+		kind, channel, ok := strings.Cut(name, ".")
+		if !ok {
+			return fmt.Errorf("invalid template name %q, expected <type>.<channel>", name)
+		}
+
+		out, err := renderer.Render(kind, channel, templates.Sample(kind))
+		if err != nil {
+			return err
+		}
+		fmt.Printf("=== %s ===\n%s\n\n", name, out)
+	}
+
+	return nil
+}
+
 // newLoginCommand creates the login command
 func newLoginCommand(cfg *config.Config) *cobra.Command {
 	return &cobra.Command{
@@ -192,6 +444,7 @@ func loginToOneDrive(cfg *config.Config) error {
 	cfg.OneDrive.AccessToken = token.AccessToken
 	cfg.OneDrive.RefreshToken = token.RefreshToken
 	cfg.OneDrive.TokenExpiry = token.Expiry.Unix()
+	cfg.OneDrive.RefreshTokenIssued = time.Now().Unix()
 
 	// Save the updated configuration
 	if err := cfg.Save(); err != nil {
@@ -206,6 +459,7 @@ func logoutFromOneDrive(cfg *config.Config) error {
 	cfg.OneDrive.AccessToken = ""
 	cfg.OneDrive.RefreshToken = ""
 	cfg.OneDrive.TokenExpiry = 0
+	cfg.OneDrive.RefreshTokenIssued = 0
 	cfg.OneDrive.MonitorPaths = []string{}
 
 	// Save the updated configuration
@@ -238,33 +492,111 @@ func setupOneDrive(cfg *config.Config) error {
 		}
 	}
 
-	// Get available folders
-	client := onedrive.NewClient(cfg.OneDrive.AccessToken)
-	folders, err := client.GetTopLevelFolders()
+	client, err := monitor.OneDriveClient(cfg)
 	if err != nil {
-		return fmt.Errorf("failed to get OneDrive folders: %w", err)
+		return fmt.Errorf("failed to connect to OneDrive: %w", err)
 	}
+	return browseFolders(reader, client, cfg)
+}
 
-	// Prompt user to select folders to monitor
-	fmt.Println("\nAvailable top-level folders:")
-	for i, folder := range folders {
-		fmt.Printf("%d. %s\n", i+1, folder.Name)
-	}
+// browseLocation is a folder visited while browsing, the drive root if id is
+// empty
+type browseLocation struct {
+	id   string
+	path string
+}
+
+// browseFolders lets the user navigate the drive and select folders to
+// monitor at any depth
+func browseFolders(reader *bufio.Reader, client *onedrive.Client, cfg *config.Config) error {
+	stack := []browseLocation{{path: "/"}}
+	for {
+		current := stack[len(stack)-1]
+
+		var folders []onedrive.Folder
+		var err error
+		if current.id == "" {
+			folders, err = client.GetTopLevelFolders()
+		} else {
+			folders, err = client.GetSubfolders(current.id)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get OneDrive folders: %w", err)
+		}
+
+		fmt.Printf("\nFolders in %s:\n", current.path)
+		for i, folder := range folders {
+			marker := ""
+			if isMonitored(cfg, folder.ID) {
+				marker = " (monitored)"
+			}
+			fmt.Printf("%d. %s%s\n", i+1, folder.Name, marker)
+		}
+		if len(folders) == 0 {
+			fmt.Println("(no subfolders)")
+		}
+
+		fmt.Println("\nEnter a number to open a folder, a path like /Backups/Restic to jump to it,")
+		fmt.Println("'m <numbers>' to monitor folders (comma-separated), 'm' to monitor this folder,")
+		fmt.Print("'..' to go up, or press Enter when done: ")
+		input, _ := reader.ReadString('\n')
+		input = strings.TrimSpace(input)
 
-	fmt.Print("\nEnter folder numbers to monitor (comma-separated): ")
-	selection, _ := reader.ReadString('\n')
-	selection = strings.TrimSpace(selection)
+		switch {
+		case input == "":
+			return nil
 
-	if selection != "" {
-		indices := strings.Split(selection, ",")
-		for _, idx := range indices {
-			if i, err := strconv.Atoi(strings.TrimSpace(idx)); err == nil && i > 0 && i <= len(folders) {
-				cfg.OneDrive.MonitorPaths = append(cfg.OneDrive.MonitorPaths, folders[i-1].ID)
+		case input == "..":
+			if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
 			}
+
+		case input == "m":
+			if current.id == "" {
+				fmt.Println("The drive root cannot be monitored, open a folder first.")
+				continue
+			}
+			monitorFolder(cfg, current.id, current.path)
+
+		case strings.HasPrefix(input, "m "):
+			for _, idx := range strings.Split(strings.TrimPrefix(input, "m "), ",") {
+				if i, err := strconv.Atoi(strings.TrimSpace(idx)); err == nil && i > 0 && i <= len(folders) {
+					monitorFolder(cfg, folders[i-1].ID, path.Join(current.path, folders[i-1].Name))
+				} else {
+					fmt.Printf("Invalid folder number: %s\n", strings.TrimSpace(idx))
+				}
+			}
+
+		case strings.HasPrefix(input, "/"):
+			folder, err := client.ResolveFolder(input)
+			if err != nil {
+				fmt.Printf("Folder %s not found: %v\n", input, err)
+				continue
+			}
+			if folder.Path == "/" {
+				stack = stack[:1]
+				continue
+			}
+			stack = append(stack[:1], browseLocation{id: folder.ID, path: folder.Path})
+
+		default:
+			i, err := strconv.Atoi(input)
+			if err != nil || i < 1 || i > len(folders) {
+				fmt.Printf("Invalid selection: %s\n", input)
+				continue
+			}
+			stack = append(stack, browseLocation{id: folders[i-1].ID, path: path.Join(current.path, folders[i-1].Name)})
 		}
 	}
+}
 
-	return nil
+// monitorFolder adds a folder selected during setup to the monitored folders
+func monitorFolder(cfg *config.Config, id, folderPath string) {
+	if cfg.AddMonitorPath(id, config.FolderInfo{Name: path.Base(folderPath), Path: folderPath}) {
+		fmt.Printf("✓ Monitoring %s\n", folderPath)
+	} else {
+		fmt.Printf("%s is already monitored\n", folderPath)
+	}
 }
 
 // setupTelegram sets up Telegram configuration
@@ -275,13 +607,26 @@ func setupTelegram(cfg *config.Config) error {
 	fmt.Println("Create a bot with @BotFather on Telegram and get the bot token.")
 	fmt.Println()
 
-	fmt.Print("Enter Telegram Bot Token: ")
+	if cfg.Telegram.BotToken != "" {
+		fmt.Printf("Enter Telegram Bot Token [%s, Enter to keep]: ", maskToken(cfg.Telegram.BotToken))
+	} else {
+		fmt.Print("Enter Telegram Bot Token: ")
+	}
 	botToken, _ := reader.ReadString('\n')
-	cfg.Telegram.BotToken = strings.TrimSpace(botToken)
+	if botToken = strings.TrimSpace(botToken); botToken != "" {
+		cfg.Telegram.BotToken = botToken
+	}
 
-	fmt.Print("Enter Telegram Chat ID: ")
+	if cfg.Telegram.ChatID != 0 {
+		fmt.Printf("Enter Telegram Chat ID [%d, Enter to keep]: ", cfg.Telegram.ChatID)
+	} else {
+		fmt.Print("Enter Telegram Chat ID: ")
+	}
 	chatIDStr, _ := reader.ReadString('\n')
 	chatIDStr = strings.TrimSpace(chatIDStr)
+	if chatIDStr == "" && cfg.Telegram.ChatID != 0 {
+		chatIDStr = strconv.FormatInt(cfg.Telegram.ChatID, 10)
+	}
 
 	if chatID, err := strconv.ParseInt(chatIDStr, 10, 64); err == nil {
 		cfg.Telegram.ChatID = chatID
@@ -289,6 +634,25 @@ func setupTelegram(cfg *config.Config) error {
 		return fmt.Errorf("invalid chat ID: %w", err)
 	}
 
+	current := make([]string, len(cfg.Telegram.AllowedChatIDs))
+	for i, id := range cfg.Telegram.AllowedChatIDs {
+		current[i] = strconv.FormatInt(id, 10)
+	}
+	allowedStr := promptValue(reader, "Enter additional chat IDs allowed to send bot commands (comma-separated, optional)", strings.Join(current, ","), false)
+	var allowed []int64
+	for _, idStr := range strings.Split(allowedStr, ",") {
+		idStr = strings.TrimSpace(idStr)
+		if idStr == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid chat ID %q: %w", idStr, err)
+		}
+		allowed = append(allowed, id)
+	}
+	cfg.Telegram.AllowedChatIDs = allowed
+
 	// Test Telegram connection
 	tg := telegram.New(cfg.Telegram.BotToken, cfg.Telegram.ChatID)
 	if err := tg.SendMessage("Backup checker setup completed successfully!"); err != nil {
@@ -299,6 +663,67 @@ func setupTelegram(cfg *config.Config) error {
 	return nil
 }
 
+// setupEmail sets up the optional SMTP server used by email routes
+func setupEmail(cfg *config.Config) error {
+	reader := bufio.NewReader(os.Stdin)
+
+	fmt.Println("\n=== Email Setup ===")
+	fmt.Println("Optionally configure an SMTP server for email notification routes. Leave empty to skip or keep the current value, enter 'none' to remove it.")
+	fmt.Println()
+
+	cfg.Email.SMTPHost = promptValue(reader, "Enter SMTP host", cfg.Email.SMTPHost, false)
+	if cfg.Email.SMTPHost == "" {
+		cfg.Email = config.EmailConfig{}
+		return nil
+	}
+
+	port := ""
+	if cfg.Email.SMTPPort != 0 {
+		port = strconv.Itoa(cfg.Email.SMTPPort)
+	}
+	portStr := promptValue(reader, "Enter SMTP port (default: 587)", port, false)
+	cfg.Email.SMTPPort = 587
+	if portStr != "" {
+		port, err := strconv.Atoi(portStr)
+		if err != nil || port <= 0 {
+			return fmt.Errorf("invalid SMTP port: %s", portStr)
+		}
+		cfg.Email.SMTPPort = port
+	}
+
+	cfg.Email.Username = promptValue(reader, "Enter SMTP username (optional)", cfg.Email.Username, false)
+	if cfg.Email.Username != "" {
+		cfg.Email.Password = promptValue(reader, "Enter SMTP password", cfg.Email.Password, true)
+	} else {
+		cfg.Email.Password = ""
+	}
+
+	cfg.Email.From = promptValue(reader, "Enter sender address", cfg.Email.From, false)
+
+	return nil
+}
+
+// setupIncidents sets up the optional PagerDuty and Opsgenie channels
+func setupIncidents(cfg *config.Config) error {
+	reader := bufio.NewReader(os.Stdin)
+
+	fmt.Println("\n=== Incident Setup ===")
+	fmt.Println("Optionally page on missed backups via PagerDuty or Opsgenie. Leave empty to skip or keep the current value, enter 'none' to remove it.")
+	fmt.Println()
+
+	cfg.PagerDuty.RoutingKey = promptValue(reader, "Enter PagerDuty Events API v2 routing key", cfg.PagerDuty.RoutingKey, true)
+	if cfg.PagerDuty.RoutingKey != "" {
+		cfg.PagerDuty.BaseURL = promptValue(reader, fmt.Sprintf("Enter PagerDuty base URL (default: %s)", pagerduty.DefaultBaseURL), cfg.PagerDuty.BaseURL, false)
+	}
+
+	cfg.Opsgenie.APIKey = promptValue(reader, "Enter Opsgenie API key", cfg.Opsgenie.APIKey, true)
+	if cfg.Opsgenie.APIKey != "" {
+		cfg.Opsgenie.BaseURL = promptValue(reader, fmt.Sprintf("Enter Opsgenie base URL (default: %s)", opsgenie.DefaultBaseURL), cfg.Opsgenie.BaseURL, false)
+	}
+
+	return nil
+}
+
 // setupMonitoring sets up monitoring configuration
 func setupMonitoring(cfg *config.Config) error {
 	reader := bufio.NewReader(os.Stdin)
@@ -314,6 +739,29 @@ func setupMonitoring(cfg *config.Config) error {
 		}
 	}
 
+	cfg.Heartbeat.URL = promptValue(reader, "Enter heartbeat URL pinged after every check (healthchecks.io / Uptime Kuma push, optional)", cfg.Heartbeat.URL, false)
+	if cfg.Heartbeat.URL != "" {
+		cfg.Heartbeat.StartURL = promptPingURL(reader, "Enter heartbeat start URL (default: <url>/start, 'none' to disable)", cfg.Heartbeat.StartURL)
+		cfg.Heartbeat.FailURL = promptPingURL(reader, "Enter heartbeat failure URL (default: <url>/fail, 'none' to disable)", cfg.Heartbeat.FailURL)
+	} else {
+		cfg.Heartbeat = config.HeartbeatConfig{}
+	}
+
+	cfg.Metrics.Listen = promptValue(reader, "Enter Prometheus metrics listen address, e.g. :9182 (optional)", cfg.Metrics.Listen, false)
+
+	cfg.Web.Listen = promptValue(reader, "Enter status dashboard listen address, e.g. :8080 (optional)", cfg.Web.Listen, false)
+	if cfg.Web.Listen != "" {
+		cfg.Web.Username = promptValue(reader, "Enter dashboard username for basic auth (optional)", cfg.Web.Username, false)
+		if cfg.Web.Username != "" {
+			cfg.Web.Password = promptValue(reader, "Enter dashboard password", cfg.Web.Password, true)
+		} else {
+			cfg.Web.Password = ""
+		}
+		cfg.Web.BearerToken = promptValue(reader, "Enter dashboard bearer token for API clients (optional)", cfg.Web.BearerToken, true)
+	} else {
+		cfg.Web = config.WebConfig{}
+	}
+
 	cfg.Monitoring.Enabled = true
 	return nil
 }
@@ -321,12 +769,121 @@ func setupMonitoring(cfg *config.Config) error {
 // showConfig displays the current configuration
 func showConfig(cfg *config.Config) {
 	fmt.Println("=== Current Configuration ===")
-	fmt.Printf("OneDrive Authenticated: %v\n", cfg.OneDrive.AccessToken != "")
-	fmt.Printf("OneDrive Monitoring Paths: %v\n", cfg.OneDrive.MonitorPaths)
-	fmt.Printf("Telegram Bot Token: %s\n", maskToken(cfg.Telegram.BotToken))
-	fmt.Printf("Telegram Chat ID: %d\n", cfg.Telegram.ChatID)
-	fmt.Printf("Check Interval: %d minutes\n", cfg.Monitoring.CheckInterval)
-	fmt.Printf("Monitoring Enabled: %v\n", cfg.Monitoring.Enabled)
+	if cfg.SettingsFile() != "" {
+		fmt.Printf("Settings File: %s\n", cfg.SettingsFile())
+	}
+	fmt.Printf("Profile: %s\n", cfg.Profile())
+	fmt.Printf("Encryption Key Source: %s\n", describeKey(cfg))
+	showField(cfg, "OneDrive Authenticated", "onedrive.access_token", cfg.OneDrive.AccessToken != "")
+	if cfg.OneDrive.RefreshTokenIssued != 0 {
+		expiry := time.Unix(cfg.OneDrive.RefreshTokenIssued, 0).Add(onedrive.RefreshTokenLifetime)
+		fmt.Printf("OneDrive Login Expires (approx.): %s\n", templates.FormatTime(expiry))
+	}
+	paths := make([]string, len(cfg.OneDrive.MonitorPaths))
+	for i, id := range cfg.OneDrive.MonitorPaths {
+		paths[i] = cfg.FolderPath(id)
+		if info := cfg.OneDrive.Folders[id]; info.Missing {
+			paths[i] += " (missing)"
+		}
+	}
+	showField(cfg, "OneDrive Monitoring Paths", "onedrive.monitor_paths", paths)
+	showField(cfg, "Telegram Bot Token", "telegram.bot_token", maskToken(cfg.Telegram.BotToken))
+	showField(cfg, "Telegram Chat ID", "telegram.chat_id", cfg.Telegram.ChatID)
+	showField(cfg, "Telegram Command Chat IDs", "telegram.allowed_chat_ids", cfg.Telegram.AllowedChatIDs)
+	showField(cfg, "SMTP Server", "email.smtp_host", fmt.Sprintf("%s:%d", cfg.Email.SMTPHost, cfg.Email.SMTPPort))
+	showField(cfg, "SMTP Password", "email.password", maskToken(cfg.Email.Password))
+	showField(cfg, "Notification Routes", "routing.routes", len(cfg.Routing.Routes))
+	showField(cfg, "PagerDuty Routing Key", "pagerduty.routing_key", maskToken(cfg.PagerDuty.RoutingKey))
+	if cfg.PagerDuty.BaseURL != "" {
+		showField(cfg, "PagerDuty Base URL", "pagerduty.base_url", cfg.PagerDuty.BaseURL)
+	}
+	showField(cfg, "Opsgenie API Key", "opsgenie.api_key", maskToken(cfg.Opsgenie.APIKey))
+	if cfg.Opsgenie.BaseURL != "" {
+		showField(cfg, "Opsgenie Base URL", "opsgenie.base_url", cfg.Opsgenie.BaseURL)
+	}
+	showField(cfg, "Check Interval", "monitoring.check_interval", fmt.Sprintf("%d minutes", cfg.Monitoring.CheckInterval))
+	showField(cfg, "Monitoring Enabled", "monitoring.enabled", cfg.Monitoring.Enabled)
+	showField(cfg, "Heartbeat URL", "heartbeat.url", cfg.Heartbeat.URL)
+	showField(cfg, "Metrics Listen Address", "metrics.listen", cfg.Metrics.Listen)
+	showField(cfg, "Dashboard Listen Address", "web.listen", cfg.Web.Listen)
+	if cfg.Web.Username != "" {
+		showField(cfg, "Dashboard Username", "web.username", cfg.Web.Username)
+		showField(cfg, "Dashboard Password", "web.password", maskToken(cfg.Web.Password))
+	}
+	showField(cfg, "Dashboard Bearer Token", "web.bearer_token", maskToken(cfg.Web.BearerToken))
+
+	if overrides := cfg.Overrides(); len(overrides) > 0 {
+		fmt.Println("\n=== Environment Overrides (not saved) ===")
+		for _, o := range overrides {
+			fmt.Printf("%s: %s\n", o.Key, o.Env)
+		}
+	}
+}
+
+// showField prints a configuration value, noting the environment variable
+// it was set from
+func showField(cfg *config.Config, label, key string, value interface{}) {
+	if env := cfg.OverriddenBy(key); env != "" {
+		fmt.Printf("%s: %v (from %s)\n", label, value, env)
+		return
+	}
+	fmt.Printf("%s: %v\n", label, value)
+}
+
+// describeKey describes the key source of the configuration file
+func describeKey(cfg *config.Config) string {
+	switch {
+	case cfg.KeySource() == "":
+		return "none (not saved yet)"
+	case cfg.KeyLocation() != "":
+		return fmt.Sprintf("%s (%s)", cfg.KeySource(), cfg.KeyLocation())
+	default:
+		return cfg.KeySource()
+	}
+}
+
+// promptValue prompts for an optional setting, showing its current value,
+// masked if secret. Empty input keeps the current value and "none" clears it.
+func promptValue(reader *bufio.Reader, label, current string, secret bool) string {
+	switch {
+	case current == "":
+		fmt.Printf("%s: ", label)
+	case secret:
+		fmt.Printf("%s [%s, Enter to keep, 'none' to clear]: ", label, maskToken(current))
+	default:
+		fmt.Printf("%s [%s, Enter to keep, 'none' to clear]: ", label, current)
+	}
+
+	input, _ := reader.ReadString('\n')
+	switch input = strings.TrimSpace(input); input {
+	case "":
+		return current
+	case "none":
+		return ""
+	default:
+		return input
+	}
+}
+
+// promptPingURL prompts for a heartbeat start or failure URL, for which
+// "none" disables the ping: empty input keeps the current value and
+// "default" resets it
+func promptPingURL(reader *bufio.Reader, label, current string) string {
+	if current != "" {
+		fmt.Printf("%s [%s, Enter to keep, 'default' to reset]: ", label, current)
+	} else {
+		fmt.Printf("%s: ", label)
+	}
+
+	input, _ := reader.ReadString('\n')
+	switch input = strings.TrimSpace(input); input {
+	case "":
+		return current
+	case "default":
+		return ""
+	default:
+		return input
+	}
 }
 
 // maskToken masks sensitive token information
@@ -339,9 +896,5 @@ func maskToken(token string) string {
 
 // confirmReset asks for confirmation before resetting configuration
 func confirmReset() bool {
-	reader := bufio.NewReader(os.Stdin)
-	fmt.Print("Are you sure you want to reset the configuration? (y/N): ")
-	response, _ := reader.ReadString('\n')
-	response = strings.TrimSpace(strings.ToLower(response))
-	return response == "y" || response == "yes"
+	return confirm("Are you sure you want to reset the configuration?")
 }
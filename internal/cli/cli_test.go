@@ -0,0 +1,53 @@
+package cli
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+)
+
+func TestPromptValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		current string
+		input   string
+		want    string
+	}{
+		{"keep", "R0UT1NGKEY", "\n", "R0UT1NGKEY"},
+		{"keep at end of input", "R0UT1NGKEY", "", "R0UT1NGKEY"},
+		{"replace", "R0UT1NGKEY", "  N3WKEY  \n", "N3WKEY"},
+		{"clear", "R0UT1NGKEY", "none\n", ""},
+		{"skip unset", "", "\n", ""},
+		{"set", "", "KEY\n", "KEY"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := bufio.NewReader(strings.NewReader(tt.input))
+			if got := promptValue(reader, "Enter key", tt.current, true); got != tt.want {
+				t.Errorf("promptValue() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPromptPingURL(t *testing.T) {
+	tests := []struct {
+		current string
+		input   string
+		want    string
+	}{
+		{"none", "\n", "none"},
+		{"none", "default\n", ""},
+		{"", "none\n", "none"},
+		{"", "\n", ""},
+		{"https://hc-ping.com/x/start", "https://example.com/start\n", "https://example.com/start"},
+	}
+
+	for _, tt := range tests {
+		reader := bufio.NewReader(strings.NewReader(tt.input))
+		if got := promptPingURL(reader, "Enter start URL", tt.current); got != tt.want {
+			t.Errorf("promptPingURL(%q, %q) = %q, want %q", tt.current, tt.input, got, tt.want)
+		}
+	}
+}
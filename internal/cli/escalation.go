@@ -0,0 +1,96 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+
+	"restic-backup-checker/internal/config"
+	"restic-backup-checker/internal/logger"
+
+	"github.com/spf13/cobra"
+)
+
+// newEscalationCommand creates the escalation command
+func newEscalationCommand(cfg *config.Config) *cobra.Command {
+	escalationCmd := &cobra.Command{
+		Use:   "escalation",
+		Short: "Manage escalation policies for long-running failures",
+		Long: `Escalation steps raise the level of a failing client after a number of consecutive
+failed checks or hours without a backup, notifying an extra route and/or raising the severity.`,
+	}
+
+	escalationCmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List escalation steps",
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(cfg.Escalation) == 0 {
+				fmt.Println("No escalation steps configured.")
+				return
+			}
+			for i, step := range cfg.Escalation {
+				fmt.Printf("Level %d: after %d failed checks or %.1f hours without backup -> route %q, severity %q\n",
+					i+1, step.AfterFailures, step.AfterHours, step.Route, step.Severity)
+			}
+		},
+	})
+
+	var step config.EscalationStep
+	addCmd := &cobra.Command{
+		Use:   "add",
+		Short: "Append an escalation step",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if step.AfterFailures <= 0 && step.AfterHours <= 0 {
+				return errors.New("an escalation step needs --after-failures or --after-hours")
+			}
+			if step.Route == "" && step.Severity == "" {
+				return errors.New("an escalation step needs --route or --severity")
+			}
+			if step.Severity != "" && !config.Severities[step.Severity] {
+				return fmt.Errorf("invalid severity %q (use info, warning, error or critical)", step.Severity)
+			}
+			if step.Route != "" && !routeExists(cfg, step.Route) {
+				return fmt.Errorf("route %s not found", step.Route)
+			}
+
+			cfg.Escalation = append(cfg.Escalation, step)
+			if err := cfg.Save(); err != nil {
+				return fmt.Errorf("failed to save configuration: %w", err)
+			}
+			logger.Info("Escalation level %d added", len(cfg.Escalation))
+			return nil
+		},
+	}
+	addCmd.Flags().IntVar(&step.AfterFailures, "after-failures", 0, "consecutive failed checks before escalating")
+	addCmd.Flags().Float64Var(&step.AfterHours, "after-hours", 0, "hours without a backup before escalating")
+	addCmd.Flags().StringVar(&step.Route, "route", "", "route additionally notified from this level")
+	addCmd.Flags().StringVar(&step.Severity, "severity", "", "severity from this level: info, warning, error or critical")
+	escalationCmd.AddCommand(addCmd)
+
+	escalationCmd.AddCommand(&cobra.Command{
+		Use:   "clear",
+		Short: "Remove all escalation steps",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg.Escalation = nil
+			if err := cfg.Save(); err != nil {
+				return fmt.Errorf("failed to save configuration: %w", err)
+			}
+			logger.Info("Escalation steps removed")
+			return nil
+		},
+	})
+
+	return escalationCmd
+}
+
+// routeExists reports whether a route name refers to a configured or the default route
+func routeExists(cfg *config.Config, name string) bool {
+	if name == "default" {
+		return true
+	}
+	for _, r := range cfg.Routing.Routes {
+		if r.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,36 @@
+package cli
+
+import (
+	"io"
+	"testing"
+
+	"restic-backup-checker/internal/config"
+)
+
+func TestEscalationAddErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"no trigger", []string{"add", "--severity", "critical"}},
+		{"no route or severity", []string{"add", "--after-failures", "3"}},
+		{"invalid severity", []string{"add", "--after-failures", "3", "--severity", "loud"}},
+		{"unknown route", []string{"add", "--after-failures", "3", "--route", "admins"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{}
+			cmd := newEscalationCommand(cfg)
+			cmd.SetArgs(tt.args)
+			cmd.SetOut(io.Discard)
+			cmd.SetErr(io.Discard)
+			if err := cmd.Execute(); err == nil {
+				t.Error("Execute() succeeded, want an error")
+			}
+			if len(cfg.Escalation) != 0 {
+				t.Errorf("Escalation = %v, want no steps added", cfg.Escalation)
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"restic-backup-checker/internal/config"
+	"restic-backup-checker/internal/logger"
+	"restic-backup-checker/internal/monitor"
+	"restic-backup-checker/internal/onedrive"
+
+	"github.com/spf13/cobra"
+)
+
+// newPathsCommand creates the paths command managing the monitored folders
+func newPathsCommand(cfg *config.Config) *cobra.Command {
+	pathsCmd := &cobra.Command{
+		Use:   "paths",
+		Short: "Manage monitored OneDrive folders",
+		Long: `Add, remove and list the monitored OneDrive folders, each containing one subfolder per client.
+Folders are given by ID or by path below the drive root, e.g. /Backups/servers or /drive/root:/Backups/servers:.
+Paths are resolved to IDs, so monitoring follows a folder that is moved or renamed, unless added with --by-path.`,
+	}
+
+	var resolve bool
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List monitored folders",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var client *onedrive.Client
+			if resolve {
+				var err error
+				if client, err = monitor.OneDriveClient(cfg); err != nil {
+					return fmt.Errorf("failed to connect to OneDrive: %w", err)
+				}
+			}
+
+			changed := false
+			for _, id := range cfg.OneDrive.MonitorPaths {
+				if client != nil {
+					folder, err := client.ResolveFolder(id)
+					if err != nil {
+						fmt.Printf("%s\t%s\t(%v)\n", id, cfg.FolderPath(id), err)
+						continue
+					}
+					info := folderInfo(id, folder)
+					changed = changed || cfg.OneDrive.Folders[id] != info
+					cfg.SetFolder(id, info)
+				}
+
+				line := id + "\t" + cfg.FolderPath(id)
+				if cfg.OneDrive.Folders[id].Missing {
+					line += "\t(missing)"
+				}
+				fmt.Println(line)
+			}
+			if changed {
+				if err := cfg.Save(); err != nil {
+					return fmt.Errorf("failed to save folder locations: %w", err)
+				}
+			}
+			return nil
+		},
+	}
+	listCmd.Flags().BoolVar(&resolve, "resolve", false, "look up the current path of every folder in OneDrive")
+	pathsCmd.AddCommand(listCmd)
+
+	var byPath bool
+	addCmd := &cobra.Command{
+		Use:   "add <folder>...",
+		Short: "Monitor folders",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := monitor.OneDriveClient(cfg)
+			if err != nil {
+				return fmt.Errorf("failed to connect to OneDrive: %w", err)
+			}
+
+			// Resolve all folders before changing anything
+			var folders []*onedrive.Folder
+			for _, arg := range args {
+				folder, err := lookupFolder(client, arg)
+				if err != nil {
+					return err
+				}
+				folders = append(folders, folder)
+			}
+
+			for _, folder := range folders {
+				ref := folder.ID
+				if byPath {
+					ref = folder.Path
+				}
+				if cfg.AddMonitorPath(ref, folderInfo(ref, folder)) {
+					logger.Info("Monitoring %s (%s)", folder.Path, folder.ID)
+				} else {
+					logger.Info("%s (%s) is already monitored", folder.Path, folder.ID)
+				}
+			}
+			if err := cfg.Save(); err != nil {
+				return fmt.Errorf("failed to save configuration: %w", err)
+			}
+			return nil
+		},
+	}
+	addCmd.Flags().BoolVar(&byPath, "by-path", false, "monitor whatever folder is at the path instead of following the folder when it is moved")
+	pathsCmd.AddCommand(addCmd)
+
+	pathsCmd.AddCommand(&cobra.Command{
+		Use:   "remove <folder>...",
+		Short: "Stop monitoring folders",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var client *onedrive.Client
+			ids := make([]string, len(args))
+			for i, arg := range args {
+				ids[i] = arg
+				folderPath, isPath := onedrive.ParsePath(arg)
+				if isMonitored(cfg, arg) || !isPath {
+					continue
+				}
+				if isMonitored(cfg, folderPath) {
+					ids[i] = folderPath
+					continue
+				}
+
+				if client == nil {
+					var err error
+					if client, err = monitor.OneDriveClient(cfg); err != nil {
+						return fmt.Errorf("failed to connect to OneDrive: %w", err)
+					}
+				}
+				folder, err := lookupFolder(client, arg)
+				if err != nil {
+					return err
+				}
+				ids[i] = folder.ID
+			}
+
+			for i, id := range ids {
+				if !cfg.RemoveMonitorPath(id) {
+					return fmt.Errorf("%s is not monitored", args[i])
+				}
+			}
+			if err := cfg.Save(); err != nil {
+				return fmt.Errorf("failed to save configuration: %w", err)
+			}
+			logger.Info("Stopped monitoring %s", strings.Join(args, ", "))
+			return nil
+		},
+	})
+
+	return pathsCmd
+}
+
+// lookupFolder finds a folder by path, if the argument starts with a slash,
+// or by ID
+func lookupFolder(client *onedrive.Client, arg string) (*onedrive.Folder, error) {
+	folder, err := client.ResolveFolder(arg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find folder %s: %w", arg, err)
+	}
+	return folder, nil
+}
+
+// folderInfo returns the location of a folder monitored by ref, an ID or path
+func folderInfo(ref string, folder *onedrive.Folder) config.FolderInfo {
+	info := config.FolderInfo{Name: folder.Name, Path: folder.Path}
+	if _, byPath := onedrive.ParsePath(ref); byPath {
+		info.ID = folder.ID
+	}
+	return info
+}
+
+// isMonitored reports whether a folder ID or path is monitored
+func isMonitored(cfg *config.Config, ref string) bool {
+	for _, p := range cfg.OneDrive.MonitorPaths {
+		if p == ref {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,197 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"restic-backup-checker/internal/config"
+	"restic-backup-checker/internal/logger"
+	"restic-backup-checker/internal/monitor"
+
+	"github.com/spf13/cobra"
+)
+
+// newProfileCommand creates the profile command managing named profiles
+func newProfileCommand() *cobra.Command {
+	profileCmd := &cobra.Command{
+		Use:   "profile",
+		Short: "Manage configuration profiles",
+		Long: `List, create and delete named profiles. Each profile has its own configuration, OneDrive login,
+key file and monitoring state. Select one with --profile or RBC_PROFILE; without either the default profile is used.`,
+		Annotations: map[string]string{noConfig: "true"},
+	}
+
+	profileCmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List profiles",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			names, err := config.Profiles()
+			if err != nil {
+				return err
+			}
+			for _, name := range names {
+				fmt.Println(name)
+			}
+			return nil
+		},
+	})
+
+	profileCmd.AddCommand(&cobra.Command{
+		Use:   "create <name>",
+		Short: "Create a profile",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := config.CreateProfile(args[0]); err != nil {
+				return err
+			}
+			logger.Info("Profile %s created, set it up with 'restic-backup-checker --profile %s login' and 'setup'", args[0], args[0])
+			return nil
+		},
+	})
+
+	var yes bool
+	deleteCmd := &cobra.Command{
+		Use:   "delete <name>",
+		Short: "Delete a profile with its configuration and state",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !yes && !confirm(fmt.Sprintf("Delete profile %s with its configuration, login and state?", args[0])) {
+				return nil
+			}
+			if err := config.DeleteProfile(args[0]); err != nil {
+				return err
+			}
+			logger.Info("Profile %s deleted", args[0])
+			return nil
+		},
+	}
+	deleteCmd.Flags().BoolVarP(&yes, "yes", "y", false, "do not ask for confirmation")
+	profileCmd.AddCommand(deleteCmd)
+
+	return profileCmd
+}
+
+// runMonitor validates the configuration and runs the monitoring service
+// until it stops
+func runMonitor(cfg *config.Config, log *logger.Logger) error {
+	if !cfg.IsConfigured() {
+		log.Info("Configuration not found. Please run 'restic-backup-checker%s setup' first.", profileFlag(cfg))
+		return nil
+	}
+
+	// Refuse to start with a broken configuration
+	if problems := configProblems(cfg); len(problems) > 0 {
+		for _, p := range problems {
+			log.Error("Invalid configuration: %s", p)
+		}
+		log.Error("Fix the configuration and check it with 'restic-backup-checker%s config validate'", profileFlag(cfg))
+		return fmt.Errorf("invalid configuration, %d problem(s)", len(problems))
+	}
+
+	// Start monitoring
+	monitor := monitor.New(cfg)
+	if err := monitor.Start(); err != nil {
+		return fmt.Errorf("monitoring stopped: %w", err)
+	}
+	return nil
+}
+
+// runProfiles runs the monitoring service of every profile concurrently. A
+// profile failing to load, start or run does not affect the others, but
+// makes runProfiles return an error once all have stopped. Nothing is
+// started if two profiles share a Telegram bot, as only one process can
+// receive its updates.
+func runProfiles(opts config.LoadOptions) error {
+	names, err := config.Profiles()
+	if err != nil {
+		return fmt.Errorf("failed to list profiles: %w", err)
+	}
+
+	var mu sync.Mutex
+	var failed []string
+	fail := func(name string) {
+		mu.Lock()
+		defer mu.Unlock()
+		failed = append(failed, name)
+	}
+
+	configs := make(map[string]*config.Config)
+	for _, name := range names {
+		opts.Profile = name
+		cfg, err := config.Load(opts)
+		if err != nil {
+			logger.With("profile", name).Error("Failed to load configuration: %v", err)
+			fail(name)
+			continue
+		}
+		configs[name] = cfg
+	}
+
+	bots := make(map[string]string)
+	for _, name := range names {
+		cfg, ok := configs[name]
+		if !ok || cfg.Telegram.BotToken == "" {
+			continue
+		}
+		if other, shared := bots[cfg.Telegram.BotToken]; shared {
+			return fmt.Errorf("profiles %s and %s use the same Telegram bot, which cannot answer both; create a bot per profile", other, name)
+		}
+		bots[cfg.Telegram.BotToken] = name
+	}
+
+	var wg sync.WaitGroup
+	for _, name := range names {
+		cfg, ok := configs[name]
+		if !ok {
+			continue
+		}
+		name, log := name, logger.With("profile", name)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// The monitor recovers panics in its own goroutines; this
+			// covers startup and the initial check
+			defer func() {
+				if r := recover(); r != nil {
+					log.Error("Monitoring stopped unexpectedly: %v", r)
+					fail(name)
+				}
+			}()
+			if err := runMonitor(cfg, log); err != nil {
+				log.Error("%v", err)
+				fail(name)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		return fmt.Errorf("profile(s) failed: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// profileFlag returns the --profile flag selecting the profile of cfg, for
+// use in hints; empty for the default profile
+func profileFlag(cfg *config.Config) string {
+	if cfg.Profile() == config.DefaultProfile {
+		return ""
+	}
+	return " --profile " + cfg.Profile()
+}
+
+// confirm asks a yes/no question on the terminal, defaulting to no
+func confirm(question string) bool {
+	reader := bufio.NewReader(os.Stdin)
+	fmt.Printf("%s (y/N): ", question)
+	response, _ := reader.ReadString('\n')
+	response = strings.TrimSpace(strings.ToLower(response))
+	return response == "y" || response == "yes"
+}
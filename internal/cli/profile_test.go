@@ -0,0 +1,49 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"restic-backup-checker/internal/config"
+	"restic-backup-checker/internal/logger"
+
+	"github.com/mitchellh/go-homedir"
+)
+
+func TestRunMonitorInvalidConfig(t *testing.T) {
+	cfg := &config.Config{}
+	if err := runMonitor(cfg, logger.With()); err != nil {
+		t.Errorf("runMonitor() = %v without configuration, want nil", err)
+	}
+
+	// Logged in, but no folders monitored
+	cfg.OneDrive.AccessToken = "token"
+	cfg.Telegram.BotToken = "123456:ABC-DEF"
+	cfg.Telegram.ChatID = 1
+	if err := runMonitor(cfg, logger.With()); err == nil {
+		t.Error("runMonitor() = nil with an invalid configuration")
+	}
+}
+
+func TestRunProfilesReportsFailedProfiles(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	homedir.DisableCache = true
+	t.Cleanup(func() { homedir.DisableCache = false })
+
+	// The default profile is not set up and is skipped; site-a cannot be
+	// loaded
+	if err := config.CreateProfile("site-a"); err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(os.Getenv("HOME"), ".config", "restic-backup-checker", "profiles", "site-a")
+	if err := os.WriteFile(filepath.Join(dir, "config.enc"), []byte("not a config"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	err := runProfiles(config.LoadOptions{})
+	if err == nil || !strings.Contains(err.Error(), "site-a") || strings.Contains(err.Error(), config.DefaultProfile) {
+		t.Errorf("runProfiles() = %v, want an error naming site-a", err)
+	}
+}
@@ -0,0 +1,231 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"restic-backup-checker/internal/config"
+	"restic-backup-checker/internal/logger"
+
+	"github.com/spf13/cobra"
+)
+
+// newRoutesCommand creates the routes command for per-client notification routing
+func newRoutesCommand(cfg *config.Config) *cobra.Command {
+	routesCmd := &cobra.Command{
+		Use:   "routes",
+		Short: "Manage notification routing",
+		Long: `Route alerts and summaries for specific clients to their own destinations.
+Clients matched by no route are sent to the default route, which falls back to the Telegram chat from setup.`,
+	}
+
+	routesCmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List notification routes",
+		Run: func(cmd *cobra.Command, args []string) {
+			for _, r := range cfg.Routing.Routes {
+				printRoute(r)
+			}
+			def := cfg.Routing.Default
+			def.Name = "default"
+			printRoute(def)
+		},
+	})
+
+	var route config.RouteConfig
+	addCmd := &cobra.Command{
+		Use:   "add <name>",
+		Short: "Add or replace a notification route",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			route.Name = args[0]
+			if len(route.Paths) == 0 && len(route.Clients) == 0 {
+				return errors.New("a route needs at least one --path or --client matcher")
+			}
+			if !route.HasDestinations() {
+				return errors.New("a route needs at least one --chat, --email or --webhook destination")
+			}
+
+			replaced := false
+			for i, r := range cfg.Routing.Routes {
+				if r.Name == route.Name {
+					cfg.Routing.Routes[i] = route
+					replaced = true
+				}
+			}
+			if !replaced {
+				cfg.Routing.Routes = append(cfg.Routing.Routes, route)
+			}
+
+			if err := cfg.Save(); err != nil {
+				return fmt.Errorf("failed to save configuration: %w", err)
+			}
+			logger.Info("Route %s saved", route.Name)
+			return nil
+		},
+	}
+	addRouteMatcherFlags(addCmd, &route)
+	addRouteDestinationFlags(addCmd, &route)
+	routesCmd.AddCommand(addCmd)
+
+	routesCmd.AddCommand(&cobra.Command{
+		Use:   "remove <name>",
+		Short: "Remove a notification route",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var kept []config.RouteConfig
+			for _, r := range cfg.Routing.Routes {
+				if r.Name != args[0] {
+					kept = append(kept, r)
+				}
+			}
+			if len(kept) == len(cfg.Routing.Routes) {
+				return fmt.Errorf("route %s not found", args[0])
+			}
+
+			cfg.Routing.Routes = kept
+			if err := cfg.Save(); err != nil {
+				return fmt.Errorf("failed to save configuration: %w", err)
+			}
+			logger.Info("Route %s removed", args[0])
+			return nil
+		},
+	})
+
+	var def config.RouteConfig
+	defaultCmd := &cobra.Command{
+		Use:   "default",
+		Short: "Set the destinations of the default route",
+		Long:  `Set the destinations for clients matched by no route. Without flags the default route falls back to the Telegram chat from setup.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg.Routing.Default = config.RouteConfig{
+				ChatIDs:  def.ChatIDs,
+				Emails:   def.Emails,
+				Webhooks: def.Webhooks,
+			}
+			if err := cfg.Save(); err != nil {
+				return fmt.Errorf("failed to save configuration: %w", err)
+			}
+			logger.Info("Default route saved")
+			return nil
+		},
+	}
+	addRouteDestinationFlags(defaultCmd, &def)
+	routesCmd.AddCommand(defaultCmd)
+
+	var quiet config.QuietHoursConfig
+	var quietOff bool
+	quietCmd := &cobra.Command{
+		Use:   "quiet <name>",
+		Short: "Set the quiet hours of a route",
+		Long: `Hold back non-critical alerts of a route during a daily time window and deliver them
+as a single digest when it ends. Use "default" for the default route.
+
+Failing clients alert with severity error unless an escalation step raises it, so with the
+default --break-through critical every alert is held back until escalation is configured.
+Use --break-through error to deliver all failures immediately and only hold back warnings.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var q *config.QuietHoursConfig
+			if !quietOff {
+				if err := validateQuietHours(quiet); err != nil {
+					return fmt.Errorf("invalid quiet hours: %w", err)
+				}
+				q = &quiet
+			}
+
+			if args[0] == "default" {
+				cfg.Routing.Default.QuietHours = q
+			} else {
+				found := false
+				for i := range cfg.Routing.Routes {
+					if cfg.Routing.Routes[i].Name == args[0] {
+						cfg.Routing.Routes[i].QuietHours = q
+						found = true
+					}
+				}
+				if !found {
+					return fmt.Errorf("route %s not found", args[0])
+				}
+			}
+
+			if err := cfg.Save(); err != nil {
+				return fmt.Errorf("failed to save configuration: %w", err)
+			}
+			logger.Info("Quiet hours of route %s saved", args[0])
+			return nil
+		},
+	}
+	quietCmd.Flags().StringVar(&quiet.Start, "start", "22:00", "start of quiet hours (HH:MM)")
+	quietCmd.Flags().StringVar(&quiet.End, "end", "07:00", "end of quiet hours (HH:MM)")
+	quietCmd.Flags().StringVar(&quiet.TimeZone, "tz", "", "IANA time zone, e.g. Europe/Berlin (default: local time)")
+	quietCmd.Flags().StringVar(&quiet.BreakThrough, "break-through", "critical", "minimum severity delivered during quiet hours: info, warning, error or critical")
+	quietCmd.Flags().BoolVar(&quietOff, "off", false, "disable quiet hours")
+	routesCmd.AddCommand(quietCmd)
+
+	return routesCmd
+}
+
+// addRouteMatcherFlags registers the flags selecting the clients of a route
+func addRouteMatcherFlags(cmd *cobra.Command, r *config.RouteConfig) {
+	cmd.Flags().StringSliceVar(&r.Paths, "path", nil, "monitored path ID whose clients match (repeatable)")
+	cmd.Flags().StringSliceVar(&r.Clients, "client", nil, "client name glob pattern, e.g. 'acme-*' (repeatable)")
+}
+
+// addRouteDestinationFlags registers the flags selecting the destinations of a route
+func addRouteDestinationFlags(cmd *cobra.Command, r *config.RouteConfig) {
+	cmd.Flags().Int64SliceVar(&r.ChatIDs, "chat", nil, "Telegram chat ID (repeatable)")
+	cmd.Flags().StringSliceVar(&r.Emails, "email", nil, "email address (repeatable)")
+	cmd.Flags().StringSliceVar(&r.Webhooks, "webhook", nil, "webhook URL (repeatable)")
+}
+
+// printRoute prints a route on one line per field
+func printRoute(r config.RouteConfig) {
+	fmt.Printf("Route: %s\n", r.Name)
+	if len(r.Paths) > 0 {
+		fmt.Printf("  Paths:    %s\n", strings.Join(r.Paths, ", "))
+	}
+	if len(r.Clients) > 0 {
+		fmt.Printf("  Clients:  %s\n", strings.Join(r.Clients, ", "))
+	}
+	if len(r.ChatIDs) > 0 {
+		fmt.Printf("  Chats:    %v\n", r.ChatIDs)
+	}
+	if len(r.Emails) > 0 {
+		fmt.Printf("  Emails:   %s\n", strings.Join(r.Emails, ", "))
+	}
+	if len(r.Webhooks) > 0 {
+		fmt.Printf("  Webhooks: %s\n", strings.Join(r.Webhooks, ", "))
+	}
+	if !r.HasDestinations() {
+		fmt.Println("  (Telegram chat from setup)")
+	}
+	if q := r.QuietHours; q != nil {
+		tz := q.TimeZone
+		if tz == "" {
+			tz = "local time"
+		}
+		fmt.Printf("  Quiet:    %s-%s %s, break through at %s\n", q.Start, q.End, tz, q.BreakThrough)
+	}
+}
+
+// validateQuietHours checks the times, time zone and severity of quiet hours
+func validateQuietHours(q config.QuietHoursConfig) error {
+	if _, err := time.Parse("15:04", q.Start); err != nil {
+		return fmt.Errorf("start %q is not HH:MM", q.Start)
+	}
+	if _, err := time.Parse("15:04", q.End); err != nil {
+		return fmt.Errorf("end %q is not HH:MM", q.End)
+	}
+	if q.TimeZone != "" {
+		if _, err := time.LoadLocation(q.TimeZone); err != nil {
+			return fmt.Errorf("unknown time zone %q", q.TimeZone)
+		}
+	}
+	if !config.Severities[q.BreakThrough] {
+		return fmt.Errorf("invalid break-through severity %q", q.BreakThrough)
+	}
+	return nil
+}
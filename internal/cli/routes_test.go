@@ -0,0 +1,34 @@
+package cli
+
+import (
+	"io"
+	"testing"
+
+	"restic-backup-checker/internal/config"
+)
+
+func TestRoutesCommandErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"no matcher", []string{"add", "acme", "--chat", "1"}},
+		{"no destination", []string{"add", "acme", "--client", "acme-*"}},
+		{"remove unknown route", []string{"remove", "acme"}},
+		{"quiet hours of unknown route", []string{"quiet", "acme"}},
+		{"invalid quiet hours", []string{"quiet", "default", "--start", "25:00"}},
+		{"invalid break-through", []string{"quiet", "default", "--break-through", "loud"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := newRoutesCommand(&config.Config{})
+			cmd.SetArgs(tt.args)
+			cmd.SetOut(io.Discard)
+			cmd.SetErr(io.Discard)
+			if err := cmd.Execute(); err == nil {
+				t.Error("Execute() succeeded, want an error")
+			}
+		})
+	}
+}
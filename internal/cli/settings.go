@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"fmt"
+
+	"restic-backup-checker/internal/config"
+	"restic-backup-checker/internal/logger"
+
+	"github.com/spf13/cobra"
+)
+
+// newConfigGetCommand creates the config get command
+func newConfigGetCommand(cfg *config.Config) *cobra.Command {
+	return &cobra.Command{
+		Use:   "get <key>",
+		Short: "Print a setting",
+		Long:  `Print the value of a setting, e.g. monitoring.check_interval. Lists are printed comma-separated, routes, escalation steps and templates as JSON.`,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			value, err := cfg.Get(args[0])
+			if err != nil {
+				return err
+			}
+			fmt.Println(value)
+			return nil
+		},
+	}
+}
+
+// newConfigSetCommand creates the config set command
+func newConfigSetCommand(cfg *config.Config) *cobra.Command {
+	return &cobra.Command{
+		Use:   "set <key> <value>",
+		Short: "Change a setting",
+		Long: `Validate and save a setting, e.g. 'config set monitoring.check_interval 30'.
+
+Lists are comma-separated or JSON, e.g. 'config set routing.default.emails a@example.com,b@example.com';
+routes, escalation steps, quiet hours and templates are JSON. Run 'config keys' for all keys.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := cfg.Set(args[0], args[1]); err != nil {
+				return err
+			}
+			if err := cfg.Save(); err != nil {
+				return fmt.Errorf("failed to save configuration: %w", err)
+			}
+			warnOverridden(cfg, args[0])
+			logger.Info("%s saved", args[0])
+			return nil
+		},
+	}
+}
+
+// newConfigUnsetCommand creates the config unset command
+func newConfigUnsetCommand(cfg *config.Config) *cobra.Command {
+	return &cobra.Command{
+		Use:   "unset <key>",
+		Short: "Reset a setting to its default",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := cfg.Unset(args[0]); err != nil {
+				return err
+			}
+			if err := cfg.Save(); err != nil {
+				return fmt.Errorf("failed to save configuration: %w", err)
+			}
+			warnOverridden(cfg, args[0])
+			logger.Info("%s reset to its default", args[0])
+			return nil
+		},
+	}
+}
+
+// newConfigKeysCommand creates the config keys command
+func newConfigKeysCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:         "keys",
+		Short:       "List the keys accepted by get, set and unset",
+		Args:        cobra.NoArgs,
+		Annotations: map[string]string{noConfig: "true"},
+		Run: func(cmd *cobra.Command, args []string) {
+			for _, key := range config.Keys() {
+				fmt.Println(key)
+			}
+		},
+	}
+}
+
+// warnOverridden warns that a saved setting has no effect while an
+// environment variable overrides it
+func warnOverridden(cfg *config.Config, key string) {
+	if env := cfg.OverriddenBy(key); env != "" {
+		logger.Warn("%s is overridden by %s, the saved value applies once it is unset", key, env)
+	}
+}
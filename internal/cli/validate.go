@@ -0,0 +1,121 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+
+	"restic-backup-checker/internal/config"
+	"restic-backup-checker/internal/monitor"
+	"restic-backup-checker/internal/telegram"
+	"restic-backup-checker/internal/templates"
+
+	"github.com/spf13/cobra"
+)
+
+// newConfigValidateCommand creates the config validate command
+func newConfigValidateCommand(cfg *config.Config) *cobra.Command {
+	var live bool
+	cmd := &cobra.Command{
+		Use:   "validate",
+		Short: "Check the configuration for problems",
+		Long: `Check the configuration and list every problem with the setting it concerns.
+With --live, also check that the monitored folders exist in OneDrive and that the Telegram bot can reach its chats.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			problems := configProblems(cfg)
+			if live {
+				problems = append(problems, probeOneDrive(cfg)...)
+				problems = append(problems, probeTelegram(cfg)...)
+			}
+
+			for _, p := range problems {
+				fmt.Println(p)
+			}
+			if len(problems) > 0 {
+				return fmt.Errorf("%d configuration problem(s) found", len(problems))
+			}
+			fmt.Println("Configuration is valid.")
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&live, "live", false, "also check folders in OneDrive and chats in Telegram")
+	return cmd
+}
+
+// configProblems returns the problems found by Validate and in the
+// configured templates
+func configProblems(cfg *config.Config) []config.Problem {
+	var problems []config.Problem
+	var verr *config.ValidationError
+	if err := cfg.Validate(); errors.As(err, &verr) {
+		problems = verr.Problems
+	}
+	if _, err := templates.New(cfg.Templates); err != nil {
+		problems = append(problems, config.Problem{Field: "templates", Message: err.Error()})
+	}
+	return problems
+}
+
+// probeOneDrive checks that every monitored folder can be resolved
+func probeOneDrive(cfg *config.Config) []config.Problem {
+	if cfg.OneDrive.AccessToken == "" {
+		return nil // reported by Validate
+	}
+
+	client, err := monitor.OneDriveClient(cfg)
+	if err != nil {
+		return []config.Problem{{Field: "onedrive.access_token", Message: fmt.Sprintf("failed to connect to OneDrive: %v", err)}}
+	}
+
+	var problems []config.Problem
+	for i, id := range cfg.OneDrive.MonitorPaths {
+		if _, err := client.ResolveFolder(id); err != nil {
+			problems = append(problems, config.Problem{
+				Field:   fmt.Sprintf("onedrive.monitor_paths[%d]", i),
+				Message: fmt.Sprintf("folder %s not found: %v", id, err),
+			})
+		}
+	}
+	return problems
+}
+
+// probeTelegram checks the bot token with getMe and every configured chat
+// with getChat
+func probeTelegram(cfg *config.Config) []config.Problem {
+	if cfg.Telegram.BotToken == "" {
+		return nil // reported by Validate
+	}
+
+	client, err := telegram.Check(cfg.Telegram.BotToken)
+	if err != nil {
+		return []config.Problem{{Field: "telegram.bot_token", Message: fmt.Sprintf("getMe failed: %v", err)}}
+	}
+
+	type chat struct {
+		field string
+		id    int64
+	}
+	var chats []chat
+	if cfg.Telegram.ChatID != 0 {
+		chats = append(chats, chat{"telegram.chat_id", cfg.Telegram.ChatID})
+	}
+	for i, id := range cfg.Routing.Default.ChatIDs {
+		chats = append(chats, chat{fmt.Sprintf("routing.default.chat_ids[%d]", i), id})
+	}
+	for i, r := range cfg.Routing.Routes {
+		for j, id := range r.ChatIDs {
+			chats = append(chats, chat{fmt.Sprintf("routing.routes[%d].chat_ids[%d]", i, j), id})
+		}
+	}
+
+	var problems []config.Problem
+	for _, c := range chats {
+		if _, err := client.ChatTitle(c.id); err != nil {
+			problems = append(problems, config.Problem{
+				Field:   c.field,
+				Message: fmt.Sprintf("bot @%s cannot access chat %d: %v", client.Username(), c.id, err),
+			})
+		}
+	}
+	return problems
+}
@@ -0,0 +1,139 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// EnvBundlePassphrase holds the passphrase of exported bundles
+const EnvBundlePassphrase = "RBC_BUNDLE_PASSPHRASE"
+
+const (
+	bundleFormat  = "restic-backup-checker-bundle"
+	bundleVersion = 1
+)
+
+// bundleHeader is the first line of an exported bundle. It is authenticated
+// as additional data.
+type bundleHeader struct {
+	Format  string `json:"format"`
+	Version int    `json:"version"`
+	Created string `json:"created"` // RFC 3339
+	Tokens  bool   `json:"tokens"`  // OAuth tokens included
+	kdfParams
+}
+
+// Export returns all settings encrypted with a passphrase, for import on
+// another host. The passphrase is taken from RBC_BUNDLE_PASSPHRASE or
+// prompted for if empty. Values set from environment variables are exported
+// as stored, not as overridden.
+func (c *Config) Export(passphrase string, withTokens bool) ([]byte, error) {
+	secret, err := bundlePassphrase(passphrase, true)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := *c.withoutOverrides()
+	if !withTokens {
+		settings = settings.withoutTokens()
+	}
+	settings.SchemaVersion = schemaVersion
+	data, err := json.Marshal(&settings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	params, err := newKDFParams()
+	if err != nil {
+		return nil, err
+	}
+	h := bundleHeader{
+		Format:    bundleFormat,
+		Version:   bundleVersion,
+		Created:   time.Now().UTC().Format(time.RFC3339),
+		Tokens:    withTokens && settings.OneDrive.RefreshToken != "",
+		kdfParams: params,
+	}
+	header, err := json.Marshal(h)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal bundle header: %w", err)
+	}
+	header = append(header, '\n')
+
+	ciphertext, err := encrypt(params.deriveKey(secret), data, header)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt bundle: %w", err)
+	}
+	return append(header, ciphertext...), nil
+}
+
+// Import replaces all settings with those of an exported bundle. The local
+// OAuth tokens are kept if withTokens is false or the bundle has none.
+// Environment overrides are dropped; Save encrypts the result with the local
+// key source.
+func (c *Config) Import(data []byte, passphrase string, withTokens bool) error {
+	if c.settingsFile != "" {
+		return fmt.Errorf("settings are read from %s, import without --config", c.settingsFile)
+	}
+
+	header, ciphertext, ok := splitHeader(data, bundleFormat)
+	if !ok {
+		return errors.New("not a restic-backup-checker bundle")
+	}
+	var h bundleHeader
+	if err := json.Unmarshal(header, &h); err != nil {
+		return fmt.Errorf("invalid bundle header: %w", err)
+	}
+	if h.Version != bundleVersion {
+		return fmt.Errorf("unsupported bundle version %d", h.Version)
+	}
+	if err := validKDFParams(h.kdfParams); err != nil {
+		return fmt.Errorf("invalid bundle header: %w", err)
+	}
+
+	secret, err := bundlePassphrase(passphrase, false)
+	if err != nil {
+		return err
+	}
+	plaintext, err := decrypt(h.deriveKey(secret), ciphertext, header)
+	if err != nil {
+		return errors.New("failed to decrypt bundle, wrong passphrase or damaged file")
+	}
+
+	var raw map[string]interface{}
+	if err := json.Unmarshal(plaintext, &raw); err != nil {
+		return fmt.Errorf("failed to unmarshal bundle: %w", err)
+	}
+	if _, err := migrate(raw); err != nil {
+		return err
+	}
+	if plaintext, err = json.Marshal(raw); err != nil {
+		return fmt.Errorf("failed to marshal migrated bundle: %w", err)
+	}
+
+	imported := defaults()
+	if err := json.Unmarshal(plaintext, &imported); err != nil {
+		return fmt.Errorf("failed to unmarshal bundle: %w", err)
+	}
+	if !withTokens || !h.Tokens {
+		imported.setTokens(c.withoutOverrides().OneDrive)
+	}
+
+	c.replace(imported)
+	return nil
+}
+
+// bundlePassphrase returns the passphrase given, set in RBC_BUNDLE_PASSPHRASE
+// or prompted for
+func bundlePassphrase(passphrase string, confirm bool) ([]byte, error) {
+	if passphrase == "" {
+		passphrase = os.Getenv(EnvBundlePassphrase)
+	}
+	if passphrase != "" {
+		return []byte(passphrase), nil
+	}
+	return promptPassphrase("Bundle passphrase", EnvBundlePassphrase, confirm)
+}
@@ -0,0 +1,140 @@
+package config
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// exportingConfig returns a config with settings and tokens to export
+func exportingConfig(t *testing.T) *Config {
+	t.Helper()
+	c := testConfig(t)
+	c.OneDrive.AccessToken = "exported-access"
+	c.OneDrive.RefreshToken = "exported-refresh"
+	c.OneDrive.MonitorPaths = []string{"/Backups/servers"}
+	c.Telegram.BotToken = "123:abc"
+	c.Telegram.ChatID = 42
+	c.Monitoring.CheckInterval = 30
+	return c
+}
+
+func TestBundleRoundTrip(t *testing.T) {
+	tests := []struct {
+		name         string
+		exportTokens bool
+		importTokens bool
+		wantRefresh  string
+	}{
+		{"with tokens", true, true, "exported-refresh"},
+		{"exported without tokens", false, true, "local-refresh"},
+		{"imported without tokens", true, false, "local-refresh"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := exportingConfig(t).Export("secret", tt.exportTokens)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			c := testConfig(t)
+			c.OneDrive.RefreshToken = "local-refresh"
+			c.Email.SMTPHost = "smtp.local"
+			if err := c.Import(data, "secret", tt.importTokens); err != nil {
+				t.Fatal(err)
+			}
+
+			if c.OneDrive.RefreshToken != tt.wantRefresh {
+				t.Errorf("refresh token = %q, want %q", c.OneDrive.RefreshToken, tt.wantRefresh)
+			}
+			if c.Telegram.ChatID != 42 || c.Monitoring.CheckInterval != 30 || len(c.OneDrive.MonitorPaths) != 1 {
+				t.Errorf("settings not imported: %+v", c)
+			}
+			if c.Email.SMTPHost != "" {
+				t.Error("local settings kept after import")
+			}
+			if c.configPath == "" || c.keyOpts.KeyFile == "" {
+				t.Error("file location or key options lost on import")
+			}
+		})
+	}
+}
+
+func TestImportRejectsBadBundles(t *testing.T) {
+	data, err := exportingConfig(t).Export("secret", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header, ciphertext, ok := splitHeader(data, bundleFormat)
+	if !ok {
+		t.Fatal("bundle has no header")
+	}
+
+	tamper := func(change func(h *bundleHeader)) []byte {
+		var h bundleHeader
+		if err := json.Unmarshal(header, &h); err != nil {
+			t.Fatal(err)
+		}
+		change(&h)
+		tampered, err := json.Marshal(h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return append(append(tampered, '\n'), ciphertext...)
+	}
+
+	tests := []struct {
+		name       string
+		data       []byte
+		passphrase string
+		want       string
+	}{
+		{"wrong passphrase", data, "other", "wrong passphrase"},
+		{"not a bundle", []byte("hello"), "secret", "not a restic-backup-checker bundle"},
+		{"zero threads", tamper(func(h *bundleHeader) { h.Threads = 0 }), "secret", "invalid bundle header"},
+		{"zero time", tamper(func(h *bundleHeader) { h.Time = 0 }), "secret", "invalid bundle header"},
+		{"huge memory", tamper(func(h *bundleHeader) { h.Memory = 1 << 31 }), "secret", "invalid bundle header"},
+		{"tokens flag", tamper(func(h *bundleHeader) { h.Tokens = false }), "secret", "wrong passphrase"},
+		{"other version", tamper(func(h *bundleHeader) { h.Version = 99 }), "secret", "unsupported bundle version"},
+		{"truncated", data[:len(data)-8], "secret", "wrong passphrase"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := testConfig(t)
+			c.Telegram.ChatID = 7
+			c.OneDrive.RefreshToken = "local-refresh"
+			err := c.Import(tt.data, tt.passphrase, true)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Import() error = %v, want one mentioning %q", err, tt.want)
+			}
+			if c.Telegram.ChatID != 7 || c.OneDrive.RefreshToken != "local-refresh" {
+				t.Errorf("local settings changed by a failed import: %+v", c)
+			}
+		})
+	}
+}
+
+func TestImportPassphraseFromEnv(t *testing.T) {
+	data, err := exportingConfig(t).Export("secret", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		passphrase string
+		wantErr    bool
+	}{
+		{"other", true},
+		{"secret", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.passphrase, func(t *testing.T) {
+			t.Setenv(EnvBundlePassphrase, tt.passphrase)
+			if err := testConfig(t).Import(data, "", true); (err != nil) != tt.wantErr {
+				t.Errorf("Import() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
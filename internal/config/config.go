@@ -1,41 +1,165 @@
 package config
 
 import (
-	"crypto/aes"
-	"crypto/cipher"
-	"crypto/rand"
-	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
 
 	"github.com/mitchellh/go-homedir"
-	"golang.org/x/crypto/pbkdf2"
 )
 
 // Config represents the application configuration
 type Config struct {
-	OneDrive      OneDriveConfig   `json:"onedrive"`
-	Telegram      TelegramConfig   `json:"telegram"`
-	Monitoring    MonitoringConfig `json:"monitoring"`
+	SchemaVersion int               `json:"schema_version"`
+	OneDrive      OneDriveConfig    `json:"onedrive"`
+	Telegram      TelegramConfig    `json:"telegram"`
+	PagerDuty     PagerDutyConfig   `json:"pagerduty"`
+	Opsgenie      OpsgenieConfig    `json:"opsgenie"`
+	Email         EmailConfig       `json:"email"`
+	Routing       RoutingConfig     `json:"routing"`
+	Escalation    []EscalationStep  `json:"escalation,omitempty"`
+	Heartbeat     HeartbeatConfig   `json:"heartbeat"`
+	Metrics       MetricsConfig     `json:"metrics"`
+	Web           WebConfig         `json:"web"`
+	Logging       LoggingConfig     `json:"logging"`
+	Monitoring    MonitoringConfig  `json:"monitoring"`
+	Templates     map[string]string `json:"templates,omitempty"` // "<type>.<channel>" -> template file
 	configPath    string
-	encryptionKey []byte
+	profile       string
+	keyOpts       KeyOptions
+	key           []byte
+	header        fileHeader
+	legacyKey     bool   // headerless file encrypted with the hostname and user key
+	settingsFile  string // YAML or TOML file the settings are read from
+	settings      []byte // settings read from settingsFile, to detect changes
+	overrides     []Override
+	lock          *os.File // held by Lock
+	migrated      bool     // loaded from an older schema version
 }
 
 // OneDriveConfig holds OneDrive authentication and configuration
 type OneDriveConfig struct {
-	AccessToken  string   `json:"access_token"`
-	RefreshToken string   `json:"refresh_token"`
-	TokenExpiry  int64    `json:"token_expiry"`
-	MonitorPaths []string `json:"monitor_paths"`
+	AccessToken        string   `json:"access_token"`
+	RefreshToken       string   `json:"refresh_token"`
+	TokenExpiry        int64    `json:"token_expiry"`
+	RefreshTokenIssued int64    `json:"refresh_token_issued,omitempty"` // when the refresh token was last renewed
+	MonitorPaths       []string `json:"monitor_paths"`                  // folder IDs, or paths such as /Backups/servers
+
+	Folders map[string]FolderInfo `json:"folders,omitempty"` // last known location of each monitored folder, by ID or path
+}
+
+// FolderInfo is the last known location of a monitored folder, refreshed on
+// every check
+type FolderInfo struct {
+	ID      string `json:"id,omitempty"` // drive item ID of a folder monitored by path
+	Name    string `json:"name"`
+	Path    string `json:"path"`              // below the drive root, e.g. /Backups/servers
+	Missing bool   `json:"missing,omitempty"` // deleted or no longer accessible
 }
 
 // TelegramConfig holds Telegram bot configuration
 type TelegramConfig struct {
-	BotToken string `json:"bot_token"`
-	ChatID   int64  `json:"chat_id"`
+	BotToken       string  `json:"bot_token"`
+	ChatID         int64   `json:"chat_id"`
+	AllowedChatIDs []int64 `json:"allowed_chat_ids,omitempty"` // extra chats allowed to send bot commands
+}
+
+// PagerDutyConfig holds PagerDuty Events API v2 configuration
+type PagerDutyConfig struct {
+	RoutingKey string `json:"routing_key"`
+	BaseURL    string `json:"base_url,omitempty"` // overrides the Events API host
+}
+
+// OpsgenieConfig holds Opsgenie Alert API configuration
+type OpsgenieConfig struct {
+	APIKey  string `json:"api_key"`
+	BaseURL string `json:"base_url,omitempty"` // overrides the Alert API host
+}
+
+// EmailConfig holds SMTP settings for email destinations
+type EmailConfig struct {
+	SMTPHost string `json:"smtp_host"`
+	SMTPPort int    `json:"smtp_port"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	From     string `json:"from"`
+}
+
+// RoutingConfig maps clients to notification destinations
+type RoutingConfig struct {
+	Routes  []RouteConfig `json:"routes,omitempty"`
+	Default RouteConfig   `json:"default"` // clients matched by no route; falls back to telegram.chat_id
+}
+
+// RouteConfig sends notifications for matching clients to a set of
+// destinations. A client matches if its monitored path is listed in Paths or
+// its name matches one of the Clients glob patterns.
+type RouteConfig struct {
+	Name     string   `json:"name"`
+	Paths    []string `json:"paths,omitempty"`
+	Clients  []string `json:"clients,omitempty"`
+	ChatIDs  []int64  `json:"chat_ids,omitempty"`
+	Emails   []string `json:"emails,omitempty"`
+	Webhooks []string `json:"webhooks,omitempty"`
+
+	QuietHours *QuietHoursConfig `json:"quiet_hours,omitempty"`
+}
+
+// QuietHoursConfig holds back non-critical alerts of a route during a daily
+// time window and delivers them as one digest when the window ends
+type QuietHoursConfig struct {
+	Start        string `json:"start"`                   // "HH:MM"
+	End          string `json:"end"`                     // "HH:MM", may be before Start to span midnight
+	TimeZone     string `json:"time_zone,omitempty"`     // IANA time zone, local time if empty
+	BreakThrough string `json:"break_through,omitempty"` // minimum severity still delivered, default critical; alerts are error unless escalated
+}
+
+// HasDestinations returns true if the route sends anywhere
+func (r RouteConfig) HasDestinations() bool {
+	return len(r.ChatIDs) > 0 || len(r.Emails) > 0 || len(r.Webhooks) > 0
+}
+
+// EscalationStep raises the escalation level of a failing client once it has
+// failed AfterFailures consecutive checks or has had no backup for AfterHours.
+// Zero conditions are ignored; steps are evaluated in order.
+type EscalationStep struct {
+	AfterFailures int     `json:"after_failures,omitempty"`
+	AfterHours    float64 `json:"after_hours,omitempty"`
+	Route         string  `json:"route,omitempty"`    // route additionally notified from this level
+	Severity      string  `json:"severity,omitempty"` // info, warning, error or critical
+}
+
+// HeartbeatConfig holds the dead man's switch pinged after every check run
+type HeartbeatConfig struct {
+	URL      string `json:"url"`                 // pinged on success, e.g. https://hc-ping.com/<uuid>
+	StartURL string `json:"start_url,omitempty"` // default URL + "/start", "none" to disable
+	FailURL  string `json:"fail_url,omitempty"`  // default URL + "/fail", "none" to disable
+}
+
+// MetricsConfig holds the Prometheus metrics listener
+type MetricsConfig struct {
+	Listen string `json:"listen"` // address serving /metrics, e.g. ":9182"; disabled if empty
+}
+
+// WebConfig holds the status dashboard and JSON API listener. Requests must
+// carry the basic auth credentials or the bearer token if either is set.
+type WebConfig struct {
+	Listen      string `json:"listen"` // address serving the dashboard, e.g. ":8080"; disabled if empty
+	Username    string `json:"username,omitempty"`
+	Password    string `json:"password,omitempty"`
+	BearerToken string `json:"bearer_token,omitempty"`
+}
+
+// LoggingConfig holds log output settings, overridden by the --log-* flags
+type LoggingConfig struct {
+	Level      string `json:"level,omitempty"`        // debug, info, warn or error; default info
+	Format     string `json:"format,omitempty"`       // text or json; default text
+	File       string `json:"file,omitempty"`         // log file, stderr if empty
+	MaxSizeMB  int    `json:"max_size_mb,omitempty"`  // rotate the log file at this size, default 10
+	MaxAgeDays int    `json:"max_age_days,omitempty"` // delete rotated files older than this
+	MaxBackups int    `json:"max_backups,omitempty"`  // number of rotated files kept
 }
 
 // MonitoringConfig holds monitoring settings
@@ -44,148 +168,215 @@ type MonitoringConfig struct {
 	Enabled       bool `json:"enabled"`
 }
 
-// Load loads the configuration from encrypted file
-func Load() (*Config, error) {
-	configPath, err := getConfigPath()
+// LoadOptions selects where the configuration is read from
+type LoadOptions struct {
+	Profile string     // named profile, default from RBC_PROFILE, else the default profile
+	File    string     // YAML or TOML settings file; the encrypted store then only holds OAuth tokens and folder locations
+	Key     KeyOptions // source of the encryption key of the encrypted store
+}
+
+// Load loads the configuration from the encrypted store, or from a settings
+// file plus the OAuth tokens in the encrypted store
+func Load(opts LoadOptions) (*Config, error) {
+	keyOpts := opts.Key.withEnv()
+	if err := keyOpts.validate(); err != nil {
+		return nil, err
+	}
+
+	profile := opts.Profile
+	if profile == "" {
+		profile = os.Getenv(EnvProfile)
+	}
+	if profile == "" {
+		profile = DefaultProfile
+	}
+	exists, err := ProfileExists(profile)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get config path: %w", err)
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("profile %s not found, create it with 'restic-backup-checker profile create %s'", profile, profile)
 	}
 
-	cfg := &Config{
-		configPath: configPath,
-		Monitoring: MonitoringConfig{
-			CheckInterval: 60, // default to 1 hour
-			Enabled:       true,
-		},
+	configPath, err := getConfigPath(profile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get config path: %w", err)
 	}
 
-	// Generate encryption key from machine-specific data
-	cfg.encryptionKey = generateEncryptionKey()
+	cfg := defaults()
+	cfg.configPath = configPath
+	cfg.profile = profile
+	cfg.keyOpts = keyOpts
+
+	if opts.File != "" {
+		if err := cfg.loadSettings(opts.File); err != nil {
+			return nil, fmt.Errorf("failed to load %s: %w", opts.File, err)
+		}
+	}
 
 	// Try to load existing config
 	if _, err := os.Stat(configPath); err == nil {
 		if err := cfg.loadFromFile(); err != nil {
-			return nil, fmt.Errorf("failed to load config: %w", err)
+			return nil, fmt.Errorf("failed to load config (the previous version is kept in %s): %w", cfg.BackupPath(), err)
+		}
+		if cfg.migrated {
+			if err := cfg.Save(); err != nil {
+				return nil, fmt.Errorf("failed to save migrated config: %w", err)
+			}
 		}
 	}
 
-	return cfg, nil
+	if err := cfg.applyEnv(); err != nil {
+		return nil, err
+	}
+
+	return &cfg, nil
 }
 
-// Save saves the configuration to encrypted file
+// Save saves the configuration to encrypted file, keeping the previous
+// version as a backup. The file is replaced atomically under the config file
+// lock. Fields overridden by environment variables keep their stored value.
+// With a settings file only the OAuth tokens and folder locations are saved,
+// and changed settings are an error.
 func (c *Config) Save() error {
-	data, err := json.Marshal(c)
+	c.SchemaVersion = schemaVersion
+	stored := c.withoutOverrides()
+	if c.settingsFile != "" {
+		if err := stored.checkSettingsUnchanged(); err != nil {
+			return err
+		}
+		stored = stored.storedOnly()
+	}
+
+	data, err := json.Marshal(stored)
 	if err != nil {
 		return fmt.Errorf("failed to marshal config: %w", err)
 	}
 
-	encrypted, err := c.encrypt(data)
+	encrypted, err := c.seal(data)
 	if err != nil {
 		return fmt.Errorf("failed to encrypt config: %w", err)
 	}
 
-	// Create config directory if it doesn't exist
-	if err := os.MkdirAll(filepath.Dir(c.configPath), 0700); err != nil {
-		return fmt.Errorf("failed to create config directory: %w", err)
+	// acquireLock creates the config directory if it doesn't exist
+	if c.lock == nil {
+		lock, err := acquireLock(c.lockPath(), true)
+		if err != nil {
+			return err
+		}
+		defer releaseLock(lock)
+	}
+
+	previous, err := os.ReadFile(c.configPath)
+	if err == nil {
+		if err := writeAtomic(c.BackupPath(), previous); err != nil {
+			return fmt.Errorf("failed to back up config file: %w", err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read config file: %w", err)
 	}
 
-	if err := os.WriteFile(c.configPath, encrypted, 0600); err != nil {
+	if err := writeAtomic(c.configPath, encrypted); err != nil {
 		return fmt.Errorf("failed to write config file: %w", err)
 	}
 
 	return nil
 }
 
-// loadFromFile loads configuration from encrypted file
+// loadFromFile loads configuration from encrypted file, migrating it to the
+// current schema version
 func (c *Config) loadFromFile() error {
+	lock, err := acquireLock(c.lockPath(), false)
+	if err != nil {
+		return err
+	}
+	defer releaseLock(lock)
+
 	encrypted, err := os.ReadFile(c.configPath)
 	if err != nil {
 		return fmt.Errorf("failed to read config file: %w", err)
 	}
 
-	decrypted, err := c.decrypt(encrypted)
+	decrypted, err := c.open(encrypted)
 	if err != nil {
 		return fmt.Errorf("failed to decrypt config: %w", err)
 	}
 
-	if err := json.Unmarshal(decrypted, c); err != nil {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(decrypted, &raw); err != nil {
 		return fmt.Errorf("failed to unmarshal config: %w", err)
 	}
-
-	return nil
-}
-
-// encrypt encrypts data using AES-GCM
-func (c *Config) encrypt(data []byte) ([]byte, error) {
-	block, err := aes.NewCipher(c.encryptionKey)
-	if err != nil {
-		return nil, err
+	if c.migrated, err = migrate(raw); err != nil {
+		return err
 	}
-
-	gcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return nil, err
-	}
-
-	nonce := make([]byte, gcm.NonceSize())
-	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
-		return nil, err
+	if decrypted, err = json.Marshal(raw); err != nil {
+		return fmt.Errorf("failed to marshal migrated config: %w", err)
 	}
 
-	ciphertext := gcm.Seal(nonce, nonce, data, nil)
-	return ciphertext, nil
-}
-
-// decrypt decrypts data using AES-GCM
-func (c *Config) decrypt(data []byte) ([]byte, error) {
-	block, err := aes.NewCipher(c.encryptionKey)
-	if err != nil {
-		return nil, err
-	}
-
-	gcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return nil, err
+	if c.settingsFile == "" {
+		if err := json.Unmarshal(decrypted, c); err != nil {
+			return fmt.Errorf("failed to unmarshal config: %w", err)
+		}
+		return nil
 	}
 
-	nonceSize := gcm.NonceSize()
-	if len(data) < nonceSize {
-		return nil, fmt.Errorf("ciphertext too short")
+	// Settings come from the settings file, only take the tokens and folder
+	// locations
+	var stored Config
+	if err := json.Unmarshal(decrypted, &stored); err != nil {
+		return fmt.Errorf("failed to unmarshal config: %w", err)
 	}
+	c.setTokens(stored.OneDrive)
+	c.OneDrive.Folders = stored.OneDrive.Folders
+	return nil
+}
 
-	nonce, ciphertext := data[:nonceSize], data[nonceSize:]
-	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+// getConfigPath returns the path to the configuration file of a profile
+func getConfigPath(profile string) (string, error) {
+	dir, err := profileDir(profile)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
-
-	return plaintext, nil
+	return filepath.Join(dir, "config.enc"), nil
 }
 
-// getConfigPath returns the path to the configuration file
-func getConfigPath() (string, error) {
+// configDir returns the directory holding the default profile and the
+// profiles directory
+func configDir() (string, error) {
 	home, err := homedir.Dir()
 	if err != nil {
 		return "", err
 	}
-
-	return filepath.Join(home, ".config", "restic-backup-checker", "config.enc"), nil
+	return filepath.Join(home, ".config", "restic-backup-checker"), nil
 }
 
-// generateEncryptionKey generates a machine-specific encryption key
-func generateEncryptionKey() []byte {
-	// Use hostname and user as base for key derivation
-	hostname, _ := os.Hostname()
-	user := os.Getenv("USER")
-	if user == "" {
-		user = os.Getenv("USERNAME")
-	}
-
-	salt := fmt.Sprintf("%s:%s", hostname, user)
-	return pbkdf2.Key([]byte(salt), []byte("restic-backup-checker-salt"), 100000, 32, sha256.New)
+// StatePath returns the path of the monitoring state file next to the config file
+func (c *Config) StatePath() string {
+	return filepath.Join(filepath.Dir(c.configPath), "state.json")
 }
 
 // IsConfigured returns true if the configuration is properly set up
 func (c *Config) IsConfigured() bool {
 	return c.OneDrive.AccessToken != "" && c.Telegram.BotToken != ""
 }
+
+// Reset clears all settings, including those set from environment
+// variables, keeping the file location and encryption key
+func (c *Config) Reset() {
+	c.replace(Config{})
+}
+
+// replace sets all settings, dropping environment overrides, and keeps the
+// file location and encryption key
+func (c *Config) replace(settings Config) {
+	settings.configPath = c.configPath
+	settings.profile = c.profile
+	settings.keyOpts = c.keyOpts
+	settings.key = c.key
+	settings.header = c.header
+	settings.legacyKey = c.legacyKey
+	settings.settingsFile = c.settingsFile
+	settings.settings = c.settings
+	settings.lock = c.lock
+	*c = settings
+}
@@ -0,0 +1,503 @@
+package config
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/term"
+)
+
+// Key sources the config encryption key can be derived from
+const (
+	KeySourcePassphrase = "passphrase" // passphrase from RBC_CONFIG_PASSPHRASE or a prompt
+	KeySourceKeyFile    = "keyfile"    // contents of a key file
+	KeySourceSystemd    = "systemd"    // systemd credential in $CREDENTIALS_DIRECTORY
+	KeySourceLegacy     = "legacy"     // hostname and user name, headerless files only
+)
+
+// Environment variables selecting the key source
+const (
+	EnvKeySource     = "RBC_KEY_SOURCE"
+	EnvKeyFile       = "RBC_KEY_FILE"
+	EnvKeyCredential = "RBC_KEY_CREDENTIAL"
+	EnvPassphrase    = "RBC_CONFIG_PASSPHRASE"
+)
+
+const (
+	fileFormat        = "restic-backup-checker-config"
+	fileVersion       = 2
+	defaultCredential = "config-key"
+	keyFileName       = "config.key"
+	saltSize          = 16
+	keySize           = 32
+)
+
+// Argon2id parameters for newly encrypted files; the parameters used are
+// stored in the file header so they can be raised later
+const (
+	argonTime    = 3
+	argonMemory  = 64 * 1024 // KiB
+	argonThreads = 4
+)
+
+// Bounds of the Argon2id parameters accepted from a file header, which may be
+// damaged or crafted
+const (
+	maxArgonTime   = 10
+	minArgonMemory = 8 * 1024    // KiB
+	maxArgonMemory = 1024 * 1024 // KiB, 1 GiB
+)
+
+// KeyOptions selects where the config encryption key comes from. Empty fields
+// are taken from the environment, then from the header of the config file.
+type KeyOptions struct {
+	Source     string // passphrase, keyfile or systemd; default keyfile for new files
+	KeyFile    string // key file, default config.key next to the config file
+	Credential string // systemd credential name, default config-key
+	Passphrase string // passphrase, prompted for on a terminal if empty
+}
+
+// fileHeader is the first line of an encrypted config file. It records how
+// the key was derived and is authenticated as additional data.
+type fileHeader struct {
+	Format     string `json:"format"`
+	Version    int    `json:"version"`
+	KeySource  string `json:"key_source"`
+	KeyFile    string `json:"key_file,omitempty"`
+	Credential string `json:"credential,omitempty"`
+	kdfParams
+}
+
+// kdfParams are the key derivation parameters in the header of a config file
+// or bundle
+type kdfParams struct {
+	KDF     string `json:"kdf"`
+	Salt    string `json:"salt"` // base64
+	Time    uint32 `json:"time"`
+	Memory  uint32 `json:"memory"` // KiB
+	Threads uint8  `json:"threads"`
+}
+
+// newKDFParams returns the parameters for a new file with a random salt
+func newKDFParams() (kdfParams, error) {
+	salt := make([]byte, saltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return kdfParams{}, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	return kdfParams{
+		KDF:     "argon2id",
+		Salt:    base64.StdEncoding.EncodeToString(salt),
+		Time:    argonTime,
+		Memory:  argonMemory,
+		Threads: argonThreads,
+	}, nil
+}
+
+// validKDFParams checks key derivation parameters read from a header before
+// any key is derived with them, as out-of-range values make Argon2 panic or
+// allocate unbounded memory
+func validKDFParams(p kdfParams) error {
+	if p.KDF != "argon2id" {
+		return fmt.Errorf("unsupported key derivation function %q", p.KDF)
+	}
+	if p.Time < 1 || p.Time > maxArgonTime {
+		return fmt.Errorf("invalid Argon2 time %d, expected 1 to %d", p.Time, maxArgonTime)
+	}
+	if p.Memory < minArgonMemory || p.Memory > maxArgonMemory {
+		return fmt.Errorf("invalid Argon2 memory %d KiB, expected %d to %d", p.Memory, minArgonMemory, maxArgonMemory)
+	}
+	if p.Threads < 1 {
+		return fmt.Errorf("invalid Argon2 threads %d, expected at least 1", p.Threads)
+	}
+	if _, err := base64.StdEncoding.DecodeString(p.Salt); err != nil {
+		return fmt.Errorf("invalid salt: %w", err)
+	}
+	return nil
+}
+
+// deriveKey derives a key from secret with validated parameters
+func (p kdfParams) deriveKey(secret []byte) []byte {
+	salt, _ := base64.StdEncoding.DecodeString(p.Salt)
+	return argon2.IDKey(secret, salt, p.Time, p.Memory, p.Threads, keySize)
+}
+
+// withEnv fills empty options from the environment
+func (o KeyOptions) withEnv() KeyOptions {
+	if o.Source == "" {
+		o.Source = os.Getenv(EnvKeySource)
+	}
+	if o.KeyFile == "" {
+		o.KeyFile = os.Getenv(EnvKeyFile)
+	}
+	if o.Credential == "" {
+		o.Credential = os.Getenv(EnvKeyCredential)
+	}
+	if o.Passphrase == "" {
+		o.Passphrase = os.Getenv(EnvPassphrase)
+	}
+	return o
+}
+
+// validate checks the key source
+func (o KeyOptions) validate() error {
+	switch o.Source {
+	case "", KeySourcePassphrase, KeySourceKeyFile, KeySourceSystemd:
+		return nil
+	default:
+		return fmt.Errorf("invalid key source %q, expected passphrase, keyfile or systemd", o.Source)
+	}
+}
+
+// KeySource returns the key source of the config file: passphrase, keyfile,
+// systemd or legacy; empty if the file has not been written yet
+func (c *Config) KeySource() string {
+	if c.legacyKey {
+		return KeySourceLegacy
+	}
+	return c.header.KeySource
+}
+
+// KeyLocation returns the key file or systemd credential the key is read
+// from, empty for other key sources
+func (c *Config) KeyLocation() string {
+	switch c.KeySource() {
+	case KeySourceKeyFile:
+		return c.keyFilePath()
+	case KeySourceSystemd:
+		return "$CREDENTIALS_DIRECTORY/" + c.credentialName()
+	default:
+		return ""
+	}
+}
+
+// Rekey re-encrypts the config file with a key from a new source and a new
+// random salt. The backup, still encrypted with the old key, is removed.
+func (c *Config) Rekey(opts KeyOptions) error {
+	if err := opts.validate(); err != nil {
+		return err
+	}
+
+	c.keyOpts = opts
+	c.key = nil
+	c.header = fileHeader{}
+	c.legacyKey = false
+	if err := c.Save(); err != nil {
+		return err
+	}
+
+	if err := os.Remove(c.BackupPath()); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove backup encrypted with the old key: %w", err)
+	}
+	return nil
+}
+
+// unlock derives the key for an existing file from its header
+func (c *Config) unlock(header fileHeader) error {
+	if header.Version != fileVersion {
+		return fmt.Errorf("unsupported config file version %d", header.Version)
+	}
+	if err := validKDFParams(header.kdfParams); err != nil {
+		return fmt.Errorf("invalid config header: %w", err)
+	}
+
+	c.header = header
+	if c.keyOpts.Source != "" {
+		c.header.KeySource = c.keyOpts.Source
+	}
+
+	secret, err := c.readSecret(false)
+	if err != nil {
+		return err
+	}
+
+	c.key = header.deriveKey(secret)
+	return nil
+}
+
+// initKey derives the key for a new file from a new random salt
+func (c *Config) initKey() error {
+	source := c.keyOpts.Source
+	if source == "" {
+		source = KeySourceKeyFile
+	}
+
+	params, err := newKDFParams()
+	if err != nil {
+		return err
+	}
+
+	c.header = fileHeader{
+		Format:     fileFormat,
+		Version:    fileVersion,
+		KeySource:  source,
+		KeyFile:    c.keyOpts.KeyFile,
+		Credential: c.keyOpts.Credential,
+		kdfParams:  params,
+	}
+
+	secret, err := c.readSecret(true)
+	if err != nil {
+		return err
+	}
+
+	c.key = params.deriveKey(secret)
+	return nil
+}
+
+// readSecret reads the key material of the header's key source. For a new
+// file a missing key file is generated and a prompted passphrase confirmed.
+func (c *Config) readSecret(create bool) ([]byte, error) {
+	switch c.header.KeySource {
+	case KeySourcePassphrase:
+		return c.passphrase(create)
+	case KeySourceKeyFile:
+		return readKeyFile(c.keyFilePath(), create)
+	case KeySourceSystemd:
+		dir := os.Getenv("CREDENTIALS_DIRECTORY")
+		if dir == "" {
+			return nil, fmt.Errorf("CREDENTIALS_DIRECTORY is not set, the systemd key source needs LoadCredential= or LoadCredentialEncrypted=")
+		}
+		return readKeyFile(filepath.Join(dir, c.credentialName()), false)
+	default:
+		return nil, fmt.Errorf("invalid key source %q, expected passphrase, keyfile or systemd", c.header.KeySource)
+	}
+}
+
+// keyFilePath returns the key file in use
+func (c *Config) keyFilePath() string {
+	switch {
+	case c.keyOpts.KeyFile != "":
+		return c.keyOpts.KeyFile
+	case c.header.KeyFile != "":
+		return c.header.KeyFile
+	default:
+		return filepath.Join(filepath.Dir(c.configPath), keyFileName)
+	}
+}
+
+// credentialName returns the systemd credential name in use
+func (c *Config) credentialName() string {
+	switch {
+	case c.keyOpts.Credential != "":
+		return c.keyOpts.Credential
+	case c.header.Credential != "":
+		return c.header.Credential
+	default:
+		return defaultCredential
+	}
+}
+
+// passphrase returns the configured passphrase or prompts for it
+func (c *Config) passphrase(confirm bool) ([]byte, error) {
+	if c.keyOpts.Passphrase != "" {
+		return []byte(c.keyOpts.Passphrase), nil
+	}
+
+	passphrase, err := promptPassphrase("Config passphrase", EnvPassphrase, confirm)
+	if err != nil {
+		return nil, err
+	}
+
+	// Ask only once per process
+	c.keyOpts.Passphrase = string(passphrase)
+	return passphrase, nil
+}
+
+// promptPassphrase prompts for a passphrase on the terminal, asking twice if
+// confirm is set. env names the variable to set when there is no terminal.
+func promptPassphrase(label, env string, confirm bool) ([]byte, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return nil, fmt.Errorf("no passphrase given, set %s", env)
+	}
+
+	fmt.Fprintf(os.Stderr, "%s: ", label)
+	passphrase, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	if len(passphrase) == 0 {
+		return nil, errors.New("passphrase must not be empty")
+	}
+
+	if confirm {
+		fmt.Fprint(os.Stderr, "Repeat passphrase: ")
+		repeated, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read passphrase: %w", err)
+		}
+		if !bytes.Equal(passphrase, repeated) {
+			return nil, errors.New("passphrases do not match")
+		}
+	}
+	return passphrase, nil
+}
+
+// readKeyFile reads key material from a file, generating a random key first
+// if create is set and the file does not exist
+func readKeyFile(path string, create bool) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && create {
+		key := make([]byte, keySize)
+		if _, err := io.ReadFull(rand.Reader, key); err != nil {
+			return nil, fmt.Errorf("failed to generate key: %w", err)
+		}
+		data = []byte(base64.StdEncoding.EncodeToString(key) + "\n")
+
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return nil, fmt.Errorf("failed to create key directory: %w", err)
+		}
+		if err := os.WriteFile(path, data, 0600); err != nil {
+			return nil, fmt.Errorf("failed to write key file: %w", err)
+		}
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+
+	secret := bytes.TrimSpace(data)
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("key file %s is empty", path)
+	}
+	return secret, nil
+}
+
+// seal encrypts the config, prefixed with the file header, or in the legacy
+// format if the file has not been rekeyed yet
+func (c *Config) seal(data []byte) ([]byte, error) {
+	if c.key == nil {
+		if err := c.initKey(); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.legacyKey {
+		return encrypt(c.key, data, nil)
+	}
+
+	header, err := json.Marshal(c.header)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config header: %w", err)
+	}
+	header = append(header, '\n')
+
+	ciphertext, err := encrypt(c.key, data, header)
+	if err != nil {
+		return nil, err
+	}
+	return append(header, ciphertext...), nil
+}
+
+// open decrypts a config file. Files without a header are in the legacy
+// format, encrypted with a key derived from the hostname and user name.
+func (c *Config) open(data []byte) ([]byte, error) {
+	header, ciphertext, ok := splitHeader(data, fileFormat)
+	if !ok {
+		c.key = generateEncryptionKey()
+		c.legacyKey = true
+		return decrypt(c.key, data, nil)
+	}
+
+	var h fileHeader
+	if err := json.Unmarshal(header, &h); err != nil {
+		return nil, fmt.Errorf("invalid config header: %w", err)
+	}
+	if err := c.unlock(h); err != nil {
+		return nil, err
+	}
+
+	plaintext, err := decrypt(c.key, ciphertext, header)
+	if err != nil {
+		return nil, fmt.Errorf("wrong key for %s key source: %w", c.header.KeySource, err)
+	}
+	return plaintext, nil
+}
+
+// splitHeader splits a config file or bundle of the given format into its
+// header line, including the newline, and the ciphertext
+func splitHeader(data []byte, format string) ([]byte, []byte, bool) {
+	prefix := `{"format":"` + format + `"`
+	if !bytes.HasPrefix(data, []byte(prefix)) {
+		return nil, nil, false
+	}
+
+	i := bytes.IndexByte(data, '\n')
+	if i < 0 {
+		return nil, nil, false
+	}
+	return data[:i+1], data[i+1:], true
+}
+
+// encrypt encrypts data using AES-GCM
+func encrypt(key, data, additionalData []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	ciphertext := gcm.Seal(nonce, nonce, data, additionalData)
+	return ciphertext, nil
+}
+
+// decrypt decrypts data using AES-GCM
+func decrypt(key, data, additionalData []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	nonceSize := gcm.NonceSize()
+	if len(data) < nonceSize {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+
+	nonce, ciphertext := data[:nonceSize], data[nonceSize:]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, additionalData)
+	if err != nil {
+		return nil, err
+	}
+
+	return plaintext, nil
+}
+
+// generateEncryptionKey generates the machine-specific key of legacy config
+// files
+func generateEncryptionKey() []byte {
+	// Use hostname and user as base for key derivation
+	hostname, _ := os.Hostname()
+	user := os.Getenv("USER")
+	if user == "" {
+		user = os.Getenv("USERNAME")
+	}
+
+	salt := fmt.Sprintf("%s:%s", hostname, user)
+	return pbkdf2.Key([]byte(salt), []byte("restic-backup-checker-salt"), 100000, 32, sha256.New)
+}
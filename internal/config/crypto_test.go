@@ -0,0 +1,154 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testConfig returns a config using a key file in a temporary directory
+func testConfig(t *testing.T) *Config {
+	t.Helper()
+	dir := t.TempDir()
+	return &Config{
+		configPath: filepath.Join(dir, "config.enc"),
+		keyOpts:    KeyOptions{Source: KeySourceKeyFile, KeyFile: filepath.Join(dir, "config.key")},
+	}
+}
+
+func TestSealOpen(t *testing.T) {
+	c := testConfig(t)
+	sealed, err := c.seal([]byte(`{"schema_version":2}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(sealed, []byte(`{"format":"`+fileFormat+`"`)) {
+		t.Fatalf("sealed file does not start with the header: %q", sealed)
+	}
+
+	reader := &Config{configPath: c.configPath, keyOpts: KeyOptions{KeyFile: c.keyOpts.KeyFile}}
+	plaintext, err := reader.open(sealed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(plaintext) != `{"schema_version":2}` {
+		t.Errorf("open() = %q", plaintext)
+	}
+}
+
+func TestOpenRejectsTamperedHeader(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(h *fileHeader)
+		want   string
+	}{
+		{"zero threads", func(h *fileHeader) { h.Threads = 0 }, "threads"},
+		{"zero time", func(h *fileHeader) { h.Time = 0 }, "time"},
+		{"huge time", func(h *fileHeader) { h.Time = 1 << 30 }, "time"},
+		{"huge memory", func(h *fileHeader) { h.Memory = 1 << 31 }, "memory"},
+		{"tiny memory", func(h *fileHeader) { h.Memory = 1 }, "memory"},
+		{"other KDF", func(h *fileHeader) { h.KDF = "scrypt" }, "key derivation function"},
+		{"bad salt", func(h *fileHeader) { h.Salt = "!" }, "salt"},
+		{"changed key source", func(h *fileHeader) { h.KeyFile = "/elsewhere" }, "wrong key"},
+	}
+
+	c := testConfig(t)
+	sealed, err := c.seal([]byte(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	header, ciphertext, ok := splitHeader(sealed, fileFormat)
+	if !ok {
+		t.Fatal("sealed file has no header")
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var h fileHeader
+			if err := json.Unmarshal(header, &h); err != nil {
+				t.Fatal(err)
+			}
+			tt.tamper(&h)
+			tampered, err := json.Marshal(h)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data := append(append(tampered, '\n'), ciphertext...)
+
+			reader := &Config{configPath: c.configPath, keyOpts: KeyOptions{KeyFile: c.keyOpts.KeyFile}}
+			_, err = reader.open(data)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("open() error = %v, want one mentioning %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidKDFParams(t *testing.T) {
+	valid := kdfParams{KDF: "argon2id", Salt: "c2FsdA==", Time: argonTime, Memory: argonMemory, Threads: argonThreads}
+	if err := validKDFParams(valid); err != nil {
+		t.Errorf("default parameters rejected: %v", err)
+	}
+
+	bounds := valid
+	bounds.Time, bounds.Memory, bounds.Threads = maxArgonTime, maxArgonMemory, 1
+	if err := validKDFParams(bounds); err != nil {
+		t.Errorf("parameters at the bounds rejected: %v", err)
+	}
+
+	over := valid
+	over.Memory = maxArgonMemory + 1
+	if err := validKDFParams(over); err == nil {
+		t.Error("memory above 1 GiB accepted")
+	}
+}
+
+func TestRekey(t *testing.T) {
+	c := testConfig(t)
+	c.Telegram.ChatID = 42
+	// The second save leaves a backup encrypted with the key file
+	for i := 0; i < 2; i++ {
+		if err := c.Save(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := c.Rekey(KeyOptions{Source: "hostname"}); err == nil {
+		t.Error("Rekey() accepted an unknown key source")
+	}
+	if err := c.Rekey(KeyOptions{Source: KeySourcePassphrase, Passphrase: "new secret"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(c.BackupPath()); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("backup encrypted with the old key kept: %v", err)
+	}
+
+	sealed, err := os.ReadFile(c.configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name       string
+		passphrase string
+		wantErr    bool
+	}{
+		{"new passphrase", "new secret", false},
+		{"wrong passphrase", "old secret", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := &Config{configPath: c.configPath, keyOpts: KeyOptions{Passphrase: tt.passphrase}}
+			plaintext, err := reader.open(sealed)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("open() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && !strings.Contains(string(plaintext), `"chat_id":42`) {
+				t.Errorf("open() = %q", plaintext)
+			}
+		})
+	}
+}
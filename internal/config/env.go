@@ -0,0 +1,80 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+)
+
+// envPrefix prefixes the environment variables overriding config fields
+const envPrefix = "RBC"
+
+// Override is a config field set from an environment variable
+type Override struct {
+	Key      string // field as in the settings file, e.g. telegram.bot_token
+	Env      string // environment variable, e.g. RBC_TELEGRAM_BOT_TOKEN
+	index    []int
+	original reflect.Value // value before the override, written by Save
+}
+
+// applyEnv overrides config fields from RBC_ environment variables. Lists
+// are comma-separated or JSON; routes, escalation steps and templates are
+// JSON.
+func (c *Config) applyEnv() error {
+	v := reflect.ValueOf(c).Elem()
+	for _, f := range configFields(v.Type(), "", envPrefix, nil) {
+		value, ok := os.LookupEnv(f.env)
+		if !ok || f.key == "schema_version" {
+			continue
+		}
+
+		field := v.FieldByIndex(f.index)
+		original := reflect.New(field.Type()).Elem()
+		original.Set(field)
+
+		parsed := reflect.New(field.Type()).Elem()
+		if err := parseValue(parsed, value); err != nil {
+			return fmt.Errorf("invalid %s: %w", f.env, err)
+		}
+		field.Set(parsed)
+
+		c.overrides = append(c.overrides, Override{Key: f.key, Env: f.env, index: f.index, original: original})
+	}
+	return nil
+}
+
+// Overrides returns the fields set from environment variables
+func (c *Config) Overrides() []Override {
+	overrides := append([]Override(nil), c.overrides...)
+	sort.Slice(overrides, func(i, j int) bool { return overrides[i].Key < overrides[j].Key })
+	return overrides
+}
+
+// OverriddenBy returns the environment variable overriding a field, empty if
+// the field is not overridden
+func (c *Config) OverriddenBy(key string) string {
+	for _, o := range c.overrides {
+		if o.Key == key {
+			return o.Env
+		}
+	}
+	return ""
+}
+
+// withoutOverrides returns a copy of the configuration with overridden
+// fields set back to their values before the override
+func (c *Config) withoutOverrides() *Config {
+	if len(c.overrides) == 0 {
+		return c
+	}
+
+	// Overridden fields are only nested in struct values, so setting them
+	// in a shallow copy leaves c untouched
+	restored := *c
+	v := reflect.ValueOf(&restored).Elem()
+	for _, o := range c.overrides {
+		v.FieldByIndex(o.index).Set(o.original)
+	}
+	return &restored
+}
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestApplyEnv(t *testing.T) {
+	t.Setenv("RBC_TELEGRAM_CHAT_ID", "-100123")
+	t.Setenv("RBC_TELEGRAM_ALLOWED_CHAT_IDS", "1, 2,")
+	t.Setenv("RBC_ONEDRIVE_MONITOR_PATHS", `["/Backups/a,b", "/Backups/c"]`)
+	t.Setenv("RBC_MONITORING_ENABLED", "true")
+	t.Setenv("RBC_ROUTING_DEFAULT_EMAILS", "ops@example.com")
+	t.Setenv("RBC_ESCALATION", `[{"after_failures": 3, "route": "admins"}]`)
+	t.Setenv("RBC_SCHEMA_VERSION", "99")
+
+	c := &Config{SchemaVersion: 1}
+	c.Telegram.ChatID = 42
+	c.OneDrive.MonitorPaths = []string{"/Backups/old"}
+	if err := c.applyEnv(); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Telegram.ChatID != -100123 {
+		t.Errorf("ChatID = %d", c.Telegram.ChatID)
+	}
+	if want := []int64{1, 2}; !reflect.DeepEqual(c.Telegram.AllowedChatIDs, want) {
+		t.Errorf("AllowedChatIDs = %v, want %v", c.Telegram.AllowedChatIDs, want)
+	}
+	if want := []string{"/Backups/a,b", "/Backups/c"}; !reflect.DeepEqual(c.OneDrive.MonitorPaths, want) {
+		t.Errorf("MonitorPaths = %v, want %v", c.OneDrive.MonitorPaths, want)
+	}
+	if !c.Monitoring.Enabled {
+		t.Error("Monitoring.Enabled not set")
+	}
+	if want := []string{"ops@example.com"}; !reflect.DeepEqual(c.Routing.Default.Emails, want) {
+		t.Errorf("Routing.Default.Emails = %v, want %v", c.Routing.Default.Emails, want)
+	}
+	if want := []EscalationStep{{AfterFailures: 3, Route: "admins"}}; !reflect.DeepEqual(c.Escalation, want) {
+		t.Errorf("Escalation = %v, want %v", c.Escalation, want)
+	}
+	if c.SchemaVersion != 1 {
+		t.Errorf("SchemaVersion overridden to %d", c.SchemaVersion)
+	}
+
+	if got := c.OverriddenBy("telegram.chat_id"); got != "RBC_TELEGRAM_CHAT_ID" {
+		t.Errorf("OverriddenBy(telegram.chat_id) = %q", got)
+	}
+	if got := c.OverriddenBy("telegram.bot_token"); got != "" {
+		t.Errorf("OverriddenBy(telegram.bot_token) = %q, want empty", got)
+	}
+	var keys []string
+	for _, o := range c.Overrides() {
+		keys = append(keys, o.Key)
+	}
+	want := []string{"escalation", "monitoring.enabled", "onedrive.monitor_paths",
+		"routing.default.emails", "telegram.allowed_chat_ids", "telegram.chat_id"}
+	if !reflect.DeepEqual(keys, want) {
+		t.Errorf("Overrides() = %v, want %v", keys, want)
+	}
+
+	restored := c.withoutOverrides()
+	if restored.Telegram.ChatID != 42 || !reflect.DeepEqual(restored.OneDrive.MonitorPaths, []string{"/Backups/old"}) ||
+		restored.Telegram.AllowedChatIDs != nil || restored.Escalation != nil || restored.Monitoring.Enabled {
+		t.Errorf("withoutOverrides() = %+v", restored)
+	}
+	if c.Telegram.ChatID != -100123 {
+		t.Error("withoutOverrides() changed the overridden config")
+	}
+}
+
+func TestApplyEnvInvalid(t *testing.T) {
+	tests := []struct {
+		env   string
+		value string
+	}{
+		{"RBC_TELEGRAM_CHAT_ID", "chat"},
+		{"RBC_MONITORING_ENABLED", "maybe"},
+		{"RBC_TELEGRAM_ALLOWED_CHAT_IDS", "1,two"},
+		{"RBC_ROUTING_ROUTES", "not json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.env, func(t *testing.T) {
+			t.Setenv(tt.env, tt.value)
+			if err := (&Config{}).applyEnv(); err == nil {
+				t.Errorf("applyEnv() accepted %s=%q", tt.env, tt.value)
+			}
+		})
+	}
+}
@@ -0,0 +1,419 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// configField is a settable config field, named by the JSON keys leading to it
+type configField struct {
+	key   string // e.g. telegram.bot_token
+	env   string // e.g. RBC_TELEGRAM_BOT_TOKEN
+	index []int
+}
+
+// managedKeys are fields that cannot be set, with the reason
+var managedKeys = map[string]string{
+	"schema_version":                "is set when the config is saved",
+	"onedrive.access_token":         "is managed by login",
+	"onedrive.refresh_token":        "is managed by login",
+	"onedrive.token_expiry":         "is managed by login",
+	"onedrive.refresh_token_issued": "is managed by login",
+	"onedrive.folders":              "is refreshed by checks",
+}
+
+// botTokenPattern matches Telegram bot tokens, e.g. 123456:ABC-DEF
+var botTokenPattern = regexp.MustCompile(`^\d+:[A-Za-z0-9_-]+$`)
+
+// defaults returns the configuration used for settings not yet saved
+func defaults() Config {
+	return Config{
+		Monitoring: MonitoringConfig{
+			CheckInterval: 60, // default to 1 hour
+			Enabled:       true,
+		},
+	}
+}
+
+// Keys returns the keys of all settable fields in declaration order
+func Keys() []string {
+	var keys []string
+	for _, f := range configFields(reflect.TypeOf(Config{}), "", envPrefix, nil) {
+		if _, managed := managedKeys[f.key]; !managed {
+			keys = append(keys, f.key)
+		}
+	}
+	return keys
+}
+
+// Get returns the value of a field, formatted as Set accepts it
+func (c *Config) Get(key string) (string, error) {
+	f, err := lookupField(key)
+	if err != nil {
+		return "", err
+	}
+	return formatValue(reflect.ValueOf(c).Elem().FieldByIndex(f.index))
+}
+
+// Set parses and validates a value and assigns it to a field. Lists are
+// comma-separated or JSON; routes, escalation steps, quiet hours and
+// templates are JSON. The configuration is validated with the new value and
+// problems with the field, such as an escalation step naming a missing
+// route, are an error. If the field is overridden by an environment
+// variable, the value is saved but the override stays in effect.
+func (c *Config) Set(key, value string) error {
+	f, err := lookupField(key)
+	if err != nil {
+		return err
+	}
+
+	field := reflect.ValueOf(c).Elem().FieldByIndex(f.index)
+	parsed := reflect.New(field.Type()).Elem()
+	if err := parseValue(parsed, value); err != nil {
+		return fmt.Errorf("invalid value for %s: %w", key, err)
+	}
+	if key == "onedrive.monitor_paths" {
+		parsed.Set(reflect.ValueOf(c.uniquePaths(parsed.Interface().([]string))))
+	}
+	if err := validateField(key, parsed.Interface()); err != nil {
+		return fmt.Errorf("invalid value for %s: %w", key, err)
+	}
+	if err := c.validateChange(key, f.index, parsed); err != nil {
+		return fmt.Errorf("invalid value for %s: %w", key, err)
+	}
+
+	c.assign(key, field, parsed)
+	return nil
+}
+
+// validateChange runs Validate on the stored settings with a field set to
+// value and returns the problems with that field or fields nested in it.
+// Problems elsewhere, such as not being logged in yet, are left to
+// 'config validate'.
+func (c *Config) validateChange(key string, index []int, value reflect.Value) error {
+	// Fields are only nested in struct values, so setting one in a shallow
+	// copy leaves c untouched
+	changed := *c.withoutOverrides()
+	changed.overrides = nil
+	reflect.ValueOf(&changed).Elem().FieldByIndex(index).Set(value)
+
+	var verr *ValidationError
+	if err := changed.Validate(); !errors.As(err, &verr) {
+		return err
+	}
+	var problems []Problem
+	for _, p := range verr.Problems {
+		if p.Field == key || strings.HasPrefix(p.Field, key+".") || strings.HasPrefix(p.Field, key+"[") {
+			problems = append(problems, p)
+		}
+	}
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// Unset resets a field to its default value
+func (c *Config) Unset(key string) error {
+	f, err := lookupField(key)
+	if err != nil {
+		return err
+	}
+
+	def := defaults()
+	field := reflect.ValueOf(c).Elem().FieldByIndex(f.index)
+	c.assign(key, field, reflect.ValueOf(&def).Elem().FieldByIndex(f.index))
+	return nil
+}
+
+// assign sets a field, or the value saved underneath its environment
+// override
+func (c *Config) assign(key string, field, value reflect.Value) {
+	for i, o := range c.overrides {
+		if o.Key == key {
+			c.overrides[i].original = value
+			return
+		}
+	}
+	field.Set(value)
+}
+
+// AddMonitorPath adds a folder ID or path to the monitored paths and records
+// the folder's location, unless the folder is already monitored by the same
+// or another reference, and reports whether it was added. Folders monitored
+// by path are compared by the ID they were last found at.
+func (c *Config) AddMonitorPath(ref string, info FolderInfo) bool {
+	id := folderID(ref, info)
+	for _, p := range c.OneDrive.MonitorPaths {
+		if p == ref {
+			c.SetFolder(ref, info)
+			return false
+		}
+		if folderID(p, c.OneDrive.Folders[p]) == id {
+			return false
+		}
+	}
+	c.OneDrive.MonitorPaths = append(c.OneDrive.MonitorPaths, ref)
+	c.SetFolder(ref, info)
+	return true
+}
+
+// folderID returns the drive item ID of a monitored folder: the ID it is
+// monitored by, or the ID its path was last found at
+func folderID(ref string, info FolderInfo) string {
+	if info.ID != "" {
+		return info.ID
+	}
+	return ref
+}
+
+// RemoveMonitorPath removes a folder ID or path from the monitored paths and
+// reports whether it was present
+func (c *Config) RemoveMonitorPath(id string) bool {
+	var kept []string
+	for _, p := range c.OneDrive.MonitorPaths {
+		if p != id {
+			kept = append(kept, p)
+		}
+	}
+	removed := len(kept) != len(c.OneDrive.MonitorPaths)
+	c.OneDrive.MonitorPaths = kept
+	delete(c.OneDrive.Folders, id)
+	return removed
+}
+
+// SetFolder records the location of a monitored folder
+func (c *Config) SetFolder(id string, info FolderInfo) {
+	if c.OneDrive.Folders == nil {
+		c.OneDrive.Folders = make(map[string]FolderInfo)
+	}
+	c.OneDrive.Folders[id] = info
+}
+
+// FolderPath returns the last known path of a monitored folder, or the ID or
+// path it is monitored by if unknown
+func (c *Config) FolderPath(id string) string {
+	if info, ok := c.OneDrive.Folders[id]; ok && info.Path != "" {
+		return info.Path
+	}
+	return id
+}
+
+// uniquePaths removes references to the same folder, by the same ID or path
+// or by a path last found at that ID, keeping the first occurrence
+func (c *Config) uniquePaths(paths []string) []string {
+	seen := make(map[string]bool)
+	var unique []string
+	for _, p := range paths {
+		id := folderID(p, c.OneDrive.Folders[p])
+		if !seen[p] && !seen[id] {
+			seen[p] = true
+			seen[id] = true
+			unique = append(unique, p)
+		}
+	}
+	return unique
+}
+
+// lookupField finds a settable field by key
+func lookupField(key string) (configField, error) {
+	if reason, managed := managedKeys[key]; managed {
+		return configField{}, fmt.Errorf("%s %s", key, reason)
+	}
+	for _, f := range configFields(reflect.TypeOf(Config{}), "", envPrefix, nil) {
+		if f.key == key {
+			return f, nil
+		}
+	}
+	return configField{}, fmt.Errorf("unknown setting %s, see 'config keys'", key)
+}
+
+// configFields lists the fields of a struct type, descending into nested
+// structs
+func configFields(t reflect.Type, key, env string, index []int) []configField {
+	var fields []configField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name := strings.Split(sf.Tag.Get("json"), ",")[0]
+		if !sf.IsExported() || name == "" || name == "-" {
+			continue
+		}
+
+		fieldKey := joinKey(key, name)
+		fieldEnv := env + "_" + strings.ToUpper(name)
+		fieldIndex := append(append([]int(nil), index...), i)
+		if sf.Type.Kind() == reflect.Struct {
+			fields = append(fields, configFields(sf.Type, fieldKey, fieldEnv, fieldIndex)...)
+			continue
+		}
+		fields = append(fields, configField{key: fieldKey, env: fieldEnv, index: fieldIndex})
+	}
+	return fields
+}
+
+// parseValue parses a string into a field value
+func parseValue(v reflect.Value, value string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		elem := v.Type().Elem().Kind()
+		if strings.HasPrefix(strings.TrimSpace(value), "[") || (elem != reflect.String && elem != reflect.Int64) {
+			return json.Unmarshal([]byte(value), v.Addr().Interface())
+		}
+
+		items := reflect.MakeSlice(v.Type(), 0, 0)
+		for _, item := range strings.Split(value, ",") {
+			item = strings.TrimSpace(item)
+			if item == "" {
+				continue
+			}
+			parsed := reflect.New(v.Type().Elem()).Elem()
+			if err := parseValue(parsed, item); err != nil {
+				return err
+			}
+			items = reflect.Append(items, parsed)
+		}
+		v.Set(items)
+	default:
+		return json.Unmarshal([]byte(value), v.Addr().Interface())
+	}
+	return nil
+}
+
+// formatValue formats a field value as parseValue accepts it
+func formatValue(v reflect.Value) (string, error) {
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 64), nil
+	case reflect.Slice:
+		if elem := v.Type().Elem().Kind(); elem == reflect.String || elem == reflect.Int64 {
+			items := make([]string, v.Len())
+			for i := range items {
+				items[i], _ = formatValue(v.Index(i))
+			}
+			return strings.Join(items, ","), nil
+		}
+	}
+
+	data, err := json.Marshal(v.Interface())
+	if err != nil {
+		return "", fmt.Errorf("failed to format value: %w", err)
+	}
+	return string(data), nil
+}
+
+// validateField checks the value of a single field; empty values mean unset
+// and are accepted
+func validateField(key string, value interface{}) error {
+	switch v := value.(type) {
+	case string:
+		if v == "" {
+			return nil
+		}
+		switch key {
+		case "telegram.bot_token":
+			if !botTokenPattern.MatchString(v) {
+				return fmt.Errorf("expected a bot token like 123456:ABC-DEF")
+			}
+		case "email.from":
+			if _, err := mail.ParseAddress(v); err != nil {
+				return err
+			}
+		case "heartbeat.url", "heartbeat.start_url", "heartbeat.fail_url", "pagerduty.base_url", "opsgenie.base_url":
+			if v == "none" && (key == "heartbeat.start_url" || key == "heartbeat.fail_url") {
+				return nil
+			}
+			return validateURL(v)
+		case "metrics.listen", "web.listen":
+			if _, _, err := net.SplitHostPort(v); err != nil {
+				return fmt.Errorf("expected host:port, e.g. :8080: %w", err)
+			}
+		case "logging.level":
+			switch strings.ToLower(v) {
+			case "debug", "info", "warn", "warning", "error":
+			default:
+				return fmt.Errorf("expected debug, info, warn or error")
+			}
+		case "logging.format":
+			if v != "text" && v != "json" {
+				return fmt.Errorf("expected text or json")
+			}
+		}
+	case int:
+		switch key {
+		case "monitoring.check_interval":
+			if v < 1 {
+				return fmt.Errorf("must be at least 1 minute")
+			}
+		case "email.smtp_port":
+			if v < 0 || v > 65535 {
+				return fmt.Errorf("must be a port between 1 and 65535")
+			}
+		default:
+			if v < 0 {
+				return fmt.Errorf("must not be negative")
+			}
+		}
+	case []string:
+		if strings.HasSuffix(key, ".emails") {
+			for _, address := range v {
+				if _, err := mail.ParseAddress(address); err != nil {
+					return fmt.Errorf("%s: %w", address, err)
+				}
+			}
+		}
+		if strings.HasSuffix(key, ".webhooks") {
+			for _, u := range v {
+				if err := validateURL(u); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// validateURL checks that a value is an absolute http or https URL
+func validateURL(value string) error {
+	u, err := url.Parse(value)
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("expected an http or https URL, got %s", value)
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAddMonitorPath(t *testing.T) {
+	byID := FolderInfo{Name: "servers", Path: "/Backups/servers"}
+	byPath := FolderInfo{ID: "01SERVERS", Name: "servers", Path: "/Backups/servers"}
+
+	tests := []struct {
+		name      string
+		monitored map[string]FolderInfo
+		ref       string
+		info      FolderInfo
+		want      bool
+	}{
+		{"first folder", nil, "01SERVERS", byID, true},
+		{"same ID", map[string]FolderInfo{"01SERVERS": byID}, "01SERVERS", byID, false},
+		{"same path", map[string]FolderInfo{"/Backups/servers": byPath}, "/Backups/servers", byPath, false},
+		{"path of a folder monitored by ID", map[string]FolderInfo{"01SERVERS": byID}, "/Backups/servers", byPath, false},
+		{"ID of a folder monitored by path", map[string]FolderInfo{"/Backups/servers": byPath}, "01SERVERS", byID, false},
+		{"other folder", map[string]FolderInfo{"01SERVERS": byID}, "01DESKTOPS", FolderInfo{Name: "desktops", Path: "/Backups/desktops"}, true},
+		{"unresolved path", map[string]FolderInfo{"/Backups/old": {Name: "old", Path: "/Backups/old"}}, "01SERVERS", byID, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{}
+			for ref, info := range tt.monitored {
+				c.OneDrive.MonitorPaths = append(c.OneDrive.MonitorPaths, ref)
+				c.SetFolder(ref, info)
+			}
+
+			if got := c.AddMonitorPath(tt.ref, tt.info); got != tt.want {
+				t.Errorf("AddMonitorPath(%q) = %v, want %v", tt.ref, got, tt.want)
+			}
+			_, recorded := c.OneDrive.Folders[tt.ref]
+			if recorded != (tt.want || tt.monitored[tt.ref] != FolderInfo{}) {
+				t.Errorf("folder location recorded = %v", recorded)
+			}
+		})
+	}
+}
+
+func TestSetMonitorPathsUnique(t *testing.T) {
+	c := &Config{}
+	c.SetFolder("/Backups/servers", FolderInfo{ID: "01SERVERS", Path: "/Backups/servers"})
+	if err := c.Set("onedrive.monitor_paths", "/Backups/servers,01SERVERS,01DESKTOPS,01DESKTOPS"); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"/Backups/servers", "01DESKTOPS"}; !reflect.DeepEqual(c.OneDrive.MonitorPaths, want) {
+		t.Errorf("MonitorPaths = %v, want %v", c.OneDrive.MonitorPaths, want)
+	}
+}
+
+func TestSet(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		value   string
+		want    string // as returned by Get, empty if Set fails
+		wantErr bool
+	}{
+		{"int", "monitoring.check_interval", "30", "30", false},
+		{"int below minimum", "monitoring.check_interval", "0", "", true},
+		{"not an int", "monitoring.check_interval", "often", "", true},
+		{"bool", "monitoring.enabled", "true", "true", false},
+		{"comma-separated list", "routing.default.emails", "a@example.com, b@example.com", "a@example.com,b@example.com", false},
+		{"JSON list", "telegram.allowed_chat_ids", "[1, -2]", "1,-2", false},
+		{"invalid email", "routing.default.emails", "not-an-address", "", true},
+		{"invalid URL", "heartbeat.url", "ftp://example.com", "", true},
+		{"bot token", "telegram.bot_token", "123456:ABC-DEF", "123456:ABC-DEF", false},
+		{"invalid bot token", "telegram.bot_token", "secret", "", true},
+		{"log format", "logging.format", "yaml", "", true},
+		{"escalation", "escalation", `[{"after_failures":3,"severity":"critical"}]`, `[{"after_failures":3,"severity":"critical"}]`, false},
+		{"escalation to a missing route", "escalation", `[{"after_failures":3,"route":"admins"}]`, "", true},
+		{"escalation without a trigger", "escalation", `[{"severity":"critical"}]`, "", true},
+		{"route without destinations", "routing.routes", `[{"name":"admins","clients":["web01"]}]`, "", true},
+		{"duplicate route", "routing.routes", `[{"name":"a","clients":["x"],"chat_ids":[1]},{"name":"a","clients":["y"],"chat_ids":[2]}]`, "", true},
+		{"quiet hours", "routing.default.quiet_hours", `{"start":"22:00","end":"07:00"}`, `{"start":"22:00","end":"07:00"}`, false},
+		{"invalid quiet hours", "routing.default.quiet_hours", `{"start":"25:00","end":"07:00"}`, "", true},
+		{"unknown key", "telegram.nope", "1", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{}
+			*c = defaults()
+			c.Telegram.ChatID = 1
+
+			err := c.Set(tt.key, tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Set(%q, %q) error = %v, wantErr %v", tt.key, tt.value, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			got, err := c.Get(tt.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Get(%q) = %q, want %q", tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetRefusesOnlyProblemsWithTheField(t *testing.T) {
+	// Not logged in and no folders monitored: Set still accepts valid values
+	c := &Config{}
+	*c = defaults()
+	if err := c.Set("web.username", "admin"); err != nil {
+		t.Errorf("Set(web.username) = %v, want nil while web.password is not set yet", err)
+	}
+	if err := c.Set("telegram.chat_id", "0"); err == nil {
+		t.Error("Set(telegram.chat_id, 0) accepted without default route destinations")
+	}
+	if err := c.Set("routing.default.chat_ids", "1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set("telegram.chat_id", "0"); err != nil {
+		t.Errorf("Set(telegram.chat_id, 0) = %v, want nil with default route destinations", err)
+	}
+}
+
+func TestSetOverridden(t *testing.T) {
+	t.Setenv("RBC_MONITORING_CHECK_INTERVAL", "5")
+	c := &Config{}
+	*c = defaults()
+	if err := c.applyEnv(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Set("monitoring.check_interval", "30"); err != nil {
+		t.Fatal(err)
+	}
+	if c.Monitoring.CheckInterval != 5 {
+		t.Errorf("CheckInterval = %d, want the override 5", c.Monitoring.CheckInterval)
+	}
+	if got := c.withoutOverrides().Monitoring.CheckInterval; got != 30 {
+		t.Errorf("saved CheckInterval = %d, want 30", got)
+	}
+}
+
+func TestUnset(t *testing.T) {
+	c := &Config{}
+	*c = defaults()
+	def := c.Monitoring.CheckInterval
+	if err := c.Set("monitoring.check_interval", "30"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set("routing.default.emails", "ops@example.com"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Unset("monitoring.check_interval"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Unset("routing.default.emails"); err != nil {
+		t.Fatal(err)
+	}
+	if c.Monitoring.CheckInterval != def {
+		t.Errorf("CheckInterval = %d, want default %d", c.Monitoring.CheckInterval, def)
+	}
+	if c.Routing.Default.Emails != nil {
+		t.Errorf("Routing.Default.Emails = %v, want nil", c.Routing.Default.Emails)
+	}
+	if err := c.Unset("telegram.nope"); err == nil {
+		t.Error("Unset(telegram.nope) succeeded")
+	}
+}
@@ -0,0 +1,218 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/mitchellh/go-homedir"
+	"gopkg.in/yaml.v3"
+)
+
+// Secret reference prefixes in settings files
+const (
+	refEnv  = "env:"  // value of an environment variable
+	refFile = "file:" // contents of a file
+	refCmd  = "cmd:"  // output of a shell command
+)
+
+// secretTimeout limits how long a cmd: reference may run
+const secretTimeout = 30 * time.Second
+
+// loadSettings reads all settings from a YAML or TOML file. Keys are the
+// JSON names of the config fields; string values may reference secrets
+// kept elsewhere.
+func (c *Config) loadSettings(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read settings file: %w", err)
+	}
+
+	var raw map[string]interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	case ".toml":
+		err = toml.Unmarshal(data, &raw)
+	default:
+		return fmt.Errorf("unsupported settings file %s, expected .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse settings file: %w", err)
+	}
+
+	if raw == nil {
+		raw = make(map[string]interface{})
+	}
+	if _, err := migrate(raw); err != nil {
+		return err
+	}
+
+	resolved, err := resolveSecrets(raw, "")
+	if err != nil {
+		return err
+	}
+
+	// Decode through JSON so the file uses the same keys as the encrypted store
+	data, err = json.Marshal(resolved)
+	if err != nil {
+		return fmt.Errorf("failed to convert settings: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(c); err != nil {
+		return fmt.Errorf("invalid settings: %w", err)
+	}
+
+	c.settingsFile = path
+	c.settings, err = json.Marshal(c.withoutStored())
+	if err != nil {
+		return fmt.Errorf("failed to marshal settings: %w", err)
+	}
+	return nil
+}
+
+// SettingsFile returns the YAML or TOML file the settings were read from,
+// empty if they are kept in the encrypted store
+func (c *Config) SettingsFile() string {
+	return c.settingsFile
+}
+
+// withoutTokens returns a copy of the configuration without the OAuth tokens
+func (c *Config) withoutTokens() Config {
+	settings := *c
+	settings.OneDrive.AccessToken = ""
+	settings.OneDrive.RefreshToken = ""
+	settings.OneDrive.TokenExpiry = 0
+	settings.OneDrive.RefreshTokenIssued = 0
+	return settings
+}
+
+// withoutStored returns a copy of the configuration without the parts kept
+// in the encrypted store when settings are read from a file
+func (c *Config) withoutStored() Config {
+	settings := c.withoutTokens()
+	settings.OneDrive.Folders = nil
+	return settings
+}
+
+// storedOnly returns the part of the configuration kept in the encrypted
+// store when settings are read from a file: the OAuth tokens and the
+// locations of the monitored folders
+func (c *Config) storedOnly() *Config {
+	return &Config{OneDrive: OneDriveConfig{
+		AccessToken:        c.OneDrive.AccessToken,
+		RefreshToken:       c.OneDrive.RefreshToken,
+		TokenExpiry:        c.OneDrive.TokenExpiry,
+		RefreshTokenIssued: c.OneDrive.RefreshTokenIssued,
+		Folders:            c.OneDrive.Folders,
+	}}
+}
+
+// checkSettingsUnchanged returns an error if settings read from a file were
+// modified, as they cannot be written back
+func (c *Config) checkSettingsUnchanged() error {
+	settings, err := json.Marshal(c.withoutStored())
+	if err != nil {
+		return fmt.Errorf("failed to marshal settings: %w", err)
+	}
+	if !bytes.Equal(settings, c.settings) {
+		return fmt.Errorf("settings are read from %s and cannot be changed here, edit that file instead", c.settingsFile)
+	}
+	return nil
+}
+
+// resolveSecrets replaces secret references in all string values of a
+// decoded settings file
+func resolveSecrets(v interface{}, key string) (interface{}, error) {
+	switch v := v.(type) {
+	case string:
+		resolved, err := resolveSecret(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		return resolved, nil
+	case map[string]interface{}:
+		for k, item := range v {
+			resolved, err := resolveSecrets(item, joinKey(key, k))
+			if err != nil {
+				return nil, err
+			}
+			v[k] = resolved
+		}
+		return v, nil
+	case []interface{}:
+		for i, item := range v {
+			resolved, err := resolveSecrets(item, fmt.Sprintf("%s[%d]", key, i))
+			if err != nil {
+				return nil, err
+			}
+			v[i] = resolved
+		}
+		return v, nil
+	case []map[string]interface{}: // TOML arrays of tables
+		for i, item := range v {
+			if _, err := resolveSecrets(item, fmt.Sprintf("%s[%d]", key, i)); err != nil {
+				return nil, err
+			}
+		}
+		return v, nil
+	default:
+		return v, nil
+	}
+}
+
+// joinKey joins the keys of nested settings with dots
+func joinKey(parent, key string) string {
+	if parent == "" {
+		return key
+	}
+	return parent + "." + key
+}
+
+// resolveSecret returns the value of an env:, file: or cmd: reference, or
+// the value itself if it is none
+func resolveSecret(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, refEnv):
+		name := strings.TrimPrefix(value, refEnv)
+		secret, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return secret, nil
+
+	case strings.HasPrefix(value, refFile):
+		path, err := homedir.Expand(strings.TrimPrefix(value, refFile))
+		if err != nil {
+			return "", fmt.Errorf("failed to expand secret file path: %w", err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret file: %w", err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+
+	case strings.HasPrefix(value, refCmd):
+		ctx, cancel := context.WithTimeout(context.Background(), secretTimeout)
+		defer cancel()
+
+		cmd := exec.CommandContext(ctx, "sh", "-c", strings.TrimPrefix(value, refCmd))
+		cmd.Stderr = os.Stderr
+		out, err := cmd.Output()
+		if err != nil {
+			return "", fmt.Errorf("failed to run secret command: %w", err)
+		}
+		return strings.TrimRight(string(out), "\r\n"), nil
+
+	default:
+		return value, nil
+	}
+}
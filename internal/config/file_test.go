@@ -0,0 +1,73 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResolveSecret(t *testing.T) {
+	t.Setenv("RBC_TEST_SECRET", "from-env")
+	secretFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(secretFile, []byte("from-file\n\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{name: "plain value", value: "123:abc", want: "123:abc"},
+		{name: "environment variable", value: "env:RBC_TEST_SECRET", want: "from-env"},
+		{name: "unset environment variable", value: "env:RBC_TEST_UNSET", wantErr: true},
+		{name: "file", value: "file:" + secretFile, want: "from-file"},
+		{name: "missing file", value: "file:" + secretFile + ".missing", wantErr: true},
+		{name: "command", value: "cmd:echo from-cmd", want: "from-cmd"},
+		{name: "failing command", value: "cmd:exit 3", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveSecret(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("resolveSecret(%q) = %q, want an error", tt.value, got)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("resolveSecret(%q) = %q, %v, want %q", tt.value, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveSecrets(t *testing.T) {
+	t.Setenv("RBC_TEST_SECRET", "from-env")
+	settings := map[string]interface{}{
+		"telegram": map[string]interface{}{"bot_token": "env:RBC_TEST_SECRET"},
+		"onedrive": map[string]interface{}{"monitor_paths": []interface{}{"env:RBC_TEST_SECRET"}},
+	}
+	if _, err := resolveSecrets(settings, ""); err != nil {
+		t.Fatal(err)
+	}
+	if got := settings["telegram"].(map[string]interface{})["bot_token"]; got != "from-env" {
+		t.Errorf("bot_token = %v, want from-env", got)
+	}
+	if got := settings["onedrive"].(map[string]interface{})["monitor_paths"].([]interface{})[0]; got != "from-env" {
+		t.Errorf("monitor_paths[0] = %v, want from-env", got)
+	}
+
+	settings = map[string]interface{}{
+		"routing": map[string]interface{}{
+			"routes": []map[string]interface{}{{"name": "env:RBC_TEST_UNSET"}},
+		},
+	}
+	_, err := resolveSecrets(settings, "")
+	if err == nil || !strings.HasPrefix(err.Error(), "routing.routes[0].name: ") {
+		t.Errorf("resolveSecrets() error = %v, want it to name routing.routes[0].name", err)
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !unix && !windows
+
+package config
+
+import "os"
+
+// lockFile does nothing on platforms without file locking
+func lockFile(f *os.File, exclusive bool) error {
+	return nil
+}
+
+// unlockFile does nothing on platforms without file locking
+func unlockFile(f *os.File) error {
+	return nil
+}
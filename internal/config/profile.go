@@ -0,0 +1,149 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+)
+
+// DefaultProfile is the profile kept directly in the config directory
+const DefaultProfile = "default"
+
+// EnvProfile selects the profile if --profile is not given
+const EnvProfile = "RBC_PROFILE"
+
+// profilesDir is the directory below the config directory holding one
+// directory per named profile
+const profilesDir = "profiles"
+
+// profileNamePattern matches valid profile names, which are also directory
+// names
+var profileNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// Profile returns the name of the profile the configuration belongs to
+func (c *Config) Profile() string {
+	return c.profile
+}
+
+// Profiles returns the default profile followed by the named profiles in
+// alphabetical order
+func Profiles() ([]string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get config directory: %w", err)
+	}
+
+	entries, err := os.ReadDir(filepath.Join(dir, profilesDir))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read profiles: %w", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		// A directory named after the default profile would be the default
+		// profile again
+		if entry.IsDir() && entry.Name() != DefaultProfile && validateProfileName(entry.Name()) == nil {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return append([]string{DefaultProfile}, names...), nil
+}
+
+// ProfileExists reports whether a profile has been created; the default
+// profile always exists
+func ProfileExists(name string) (bool, error) {
+	if name == DefaultProfile {
+		return true, nil
+	}
+	dir, err := profileDir(name)
+	if err != nil {
+		return false, err
+	}
+	if _, err := os.Stat(dir); errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// CreateProfile creates the directory of a new named profile
+func CreateProfile(name string) error {
+	if name == DefaultProfile {
+		return fmt.Errorf("the %s profile always exists", DefaultProfile)
+	}
+	exists, err := ProfileExists(name)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("profile %s already exists", name)
+	}
+
+	dir, err := profileDir(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create profile directory: %w", err)
+	}
+	return nil
+}
+
+// DeleteProfile removes a named profile with its configuration, key file and
+// state. It waits for other processes using the profile's config file.
+func DeleteProfile(name string) error {
+	if name == DefaultProfile {
+		return fmt.Errorf("the %s profile cannot be deleted, use 'config reset'", DefaultProfile)
+	}
+	exists, err := ProfileExists(name)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("profile %s not found", name)
+	}
+
+	dir, err := profileDir(name)
+	if err != nil {
+		return err
+	}
+	lock, err := acquireLock(filepath.Join(dir, "config.enc.lock"), true)
+	if err != nil {
+		return err
+	}
+	releaseLock(lock)
+
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to remove profile directory: %w", err)
+	}
+	return nil
+}
+
+// profileDir returns the directory holding the files of a profile
+func profileDir(name string) (string, error) {
+	if err := validateProfileName(name); err != nil {
+		return "", err
+	}
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	if name == DefaultProfile {
+		return dir, nil
+	}
+	return filepath.Join(dir, profilesDir, name), nil
+}
+
+// validateProfileName checks that a profile name is usable as a directory
+// name
+func validateProfileName(name string) error {
+	if !profileNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid profile name %q, use letters, digits, '.', '_' and '-'", name)
+	}
+	return nil
+}
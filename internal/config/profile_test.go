@@ -0,0 +1,67 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mitchellh/go-homedir"
+)
+
+// useHome points the config directory at a temporary home directory
+func useHome(t *testing.T) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	homedir.DisableCache = true
+	t.Cleanup(func() { homedir.DisableCache = false })
+}
+
+func TestProfiles(t *testing.T) {
+	useHome(t)
+
+	for _, name := range []string{"site-b", "site-a"} {
+		if err := CreateProfile(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	names, err := Profiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{DefaultProfile, "site-a", "site-b"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Profiles() = %v, want %v", names, want)
+	}
+
+	if err := DeleteProfile("site-a"); err != nil {
+		t.Fatal(err)
+	}
+	if exists, err := ProfileExists("site-a"); err != nil || exists {
+		t.Errorf("ProfileExists(site-a) = %v, %v after delete", exists, err)
+	}
+}
+
+func TestProfileErrors(t *testing.T) {
+	useHome(t)
+	if err := CreateProfile("site-a"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		run  func() error
+	}{
+		{"create default", func() error { return CreateProfile(DefaultProfile) }},
+		{"create existing", func() error { return CreateProfile("site-a") }},
+		{"create with slash", func() error { return CreateProfile("../site-a") }},
+		{"create hidden", func() error { return CreateProfile(".site") }},
+		{"delete default", func() error { return DeleteProfile(DefaultProfile) }},
+		{"delete missing", func() error { return DeleteProfile("site-b") }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.run(); err == nil {
+				t.Error("succeeded, want an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// schemaVersion is the version of the stored configuration written by Save
+const schemaVersion = 1
+
+// migrations upgrade a decoded configuration from the schema version of
+// their index to the next one
+var migrations = []func(raw map[string]interface{}) error{
+	migrateUniquePaths, // 0 -> 1
+}
+
+// lockTimeout limits how long Load and Save wait for another process
+// holding the config file lock
+const lockTimeout = 30 * time.Second
+
+// Lock takes the exclusive lock on the config file, waiting for other
+// processes to release it, and reloads the OAuth tokens they may have saved
+// in the meantime. Saves keep the lock until Unlock is called, so a token
+// can be checked, refreshed and saved without another process interfering.
+func (c *Config) Lock() error {
+	if c.lock != nil {
+		return errors.New("config file is already locked")
+	}
+
+	lock, err := acquireLock(c.lockPath(), true)
+	if err != nil {
+		return err
+	}
+	c.lock = lock
+
+	if err := c.reloadTokens(); err != nil {
+		c.Unlock()
+		return err
+	}
+	return nil
+}
+
+// Unlock releases the lock taken by Lock
+func (c *Config) Unlock() {
+	if c.lock != nil {
+		releaseLock(c.lock)
+		c.lock = nil
+	}
+}
+
+// BackupPath returns the path of the previous generation of the config file
+func (c *Config) BackupPath() string {
+	return c.configPath + ".bak"
+}
+
+// lockPath returns the path of the lock file next to the config file
+func (c *Config) lockPath() string {
+	return c.configPath + ".lock"
+}
+
+// reloadTokens reads the OAuth tokens from the config file, if it exists
+func (c *Config) reloadTokens() error {
+	encrypted, err := os.ReadFile(c.configPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	decrypted, err := c.open(encrypted)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt config: %w", err)
+	}
+
+	var stored Config
+	if err := json.Unmarshal(decrypted, &stored); err != nil {
+		return fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+	c.setTokens(stored.OneDrive)
+	return nil
+}
+
+// setTokens copies the OAuth tokens from a stored configuration. Tokens set
+// from environment variables are kept, the stored ones would replace them.
+func (c *Config) setTokens(stored OneDriveConfig) {
+	if c.OverriddenBy("onedrive.access_token") == "" {
+		c.OneDrive.AccessToken = stored.AccessToken
+	}
+	if c.OverriddenBy("onedrive.refresh_token") == "" {
+		c.OneDrive.RefreshToken = stored.RefreshToken
+	}
+	if c.OverriddenBy("onedrive.token_expiry") == "" {
+		c.OneDrive.TokenExpiry = stored.TokenExpiry
+	}
+	if c.OverriddenBy("onedrive.refresh_token_issued") == "" {
+		c.OneDrive.RefreshTokenIssued = stored.RefreshTokenIssued
+	}
+}
+
+// acquireLock opens the lock file and locks it, retrying until lockTimeout
+func acquireLock(path string, exclusive bool) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		err := lockFile(f, exclusive)
+		if err == nil {
+			return f, nil
+		}
+		if time.Now().After(deadline) {
+			f.Close()
+			return nil, fmt.Errorf("config file is locked by another process: %w", err)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// releaseLock unlocks and closes a lock file
+func releaseLock(f *os.File) {
+	unlockFile(f)
+	f.Close()
+}
+
+// writeAtomic replaces a file by writing a temporary file in the same
+// directory, syncing it to disk and renaming it over the old one
+func writeAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // fails harmlessly once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	syncDir(dir)
+	return nil
+}
+
+// syncDir syncs a directory so a rename in it is durable. This is best
+// effort: directories cannot be synced on all platforms.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	d.Sync()
+	d.Close()
+}
+
+// migrate upgrades a decoded configuration to the current schema version
+// and reports whether it was changed
+func migrate(raw map[string]interface{}) (bool, error) {
+	version := 0
+	switch v := raw["schema_version"].(type) {
+	case float64: // JSON
+		version = int(v)
+	case int: // YAML
+		version = v
+	case int64: // TOML
+		version = int(v)
+	}
+
+	if version > schemaVersion {
+		return false, fmt.Errorf("config schema version %d is newer than the supported version %d, upgrade restic-backup-checker", version, schemaVersion)
+	}
+
+	migrated := version < schemaVersion
+	for ; version < schemaVersion; version++ {
+		if err := migrations[version](raw); err != nil {
+			return false, fmt.Errorf("failed to migrate config to schema version %d: %w", version+1, err)
+		}
+	}
+	raw["schema_version"] = schemaVersion
+	return migrated, nil
+}
+
+// migrateUniquePaths removes the duplicate monitored folders that running
+// setup repeatedly used to add
+func migrateUniquePaths(raw map[string]interface{}) error {
+	onedrive, ok := raw["onedrive"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	paths, ok := onedrive["monitor_paths"].([]interface{})
+	if !ok {
+		return nil
+	}
+
+	seen := make(map[interface{}]bool)
+	var unique []interface{}
+	for _, p := range paths {
+		if !seen[p] {
+			seen[p] = true
+			unique = append(unique, p)
+		}
+	}
+	onedrive["monitor_paths"] = unique
+	return nil
+}
@@ -0,0 +1,108 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLockKeepsTokensFromEnv(t *testing.T) {
+	stored := testConfig(t)
+	stored.OneDrive.AccessToken = "stored-access"
+	stored.OneDrive.RefreshToken = "stored-refresh"
+	stored.OneDrive.TokenExpiry = 100
+	if err := stored.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("RBC_ONEDRIVE_REFRESH_TOKEN", "env-refresh")
+	c := &Config{configPath: stored.configPath, keyOpts: stored.keyOpts}
+	if err := c.applyEnv(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Another process refreshes the access token in the meantime
+	stored.OneDrive.AccessToken = "refreshed-access"
+	if err := stored.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Lock(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Unlock()
+
+	if c.OneDrive.RefreshToken != "env-refresh" {
+		t.Errorf("RefreshToken = %q, want the one from the environment", c.OneDrive.RefreshToken)
+	}
+	if c.OneDrive.AccessToken != "refreshed-access" || c.OneDrive.TokenExpiry != 100 {
+		t.Errorf("AccessToken = %q, TokenExpiry = %d, want the saved ones", c.OneDrive.AccessToken, c.OneDrive.TokenExpiry)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	tests := []struct {
+		name         string
+		version      interface{}
+		paths        []interface{}
+		wantPaths    []interface{}
+		wantMigrated bool
+		wantErr      bool
+	}{
+		{
+			name:         "unversioned",
+			paths:        []interface{}{"/a", "/b", "/a"},
+			wantPaths:    []interface{}{"/a", "/b"},
+			wantMigrated: true,
+		},
+		{
+			name:         "version 0 from JSON",
+			version:      float64(0),
+			paths:        []interface{}{"/a", "/a"},
+			wantPaths:    []interface{}{"/a"},
+			wantMigrated: true,
+		},
+		{name: "current from JSON", version: float64(schemaVersion), paths: []interface{}{"/a", "/a"}, wantPaths: []interface{}{"/a", "/a"}},
+		{name: "current from YAML", version: schemaVersion, paths: []interface{}{"/a"}, wantPaths: []interface{}{"/a"}},
+		{name: "current from TOML", version: int64(schemaVersion), paths: []interface{}{"/a"}, wantPaths: []interface{}{"/a"}},
+		{name: "newer", version: float64(schemaVersion + 1), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := map[string]interface{}{"onedrive": map[string]interface{}{"monitor_paths": tt.paths}}
+			if tt.version != nil {
+				raw["schema_version"] = tt.version
+			}
+
+			migrated, err := migrate(raw)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("migrate() accepted a newer schema version")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if migrated != tt.wantMigrated {
+				t.Errorf("migrate() = %v, want %v", migrated, tt.wantMigrated)
+			}
+			if raw["schema_version"] != schemaVersion {
+				t.Errorf("schema_version = %v, want %d", raw["schema_version"], schemaVersion)
+			}
+			if got := raw["onedrive"].(map[string]interface{})["monitor_paths"]; !reflect.DeepEqual(got, tt.wantPaths) {
+				t.Errorf("monitor_paths = %v, want %v", got, tt.wantPaths)
+			}
+		})
+	}
+}
+
+func TestMigrateWithoutOneDrive(t *testing.T) {
+	raw := map[string]interface{}{"telegram": map[string]interface{}{"chat_id": float64(1)}}
+	if _, err := migrate(raw); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := raw["onedrive"]; ok {
+		t.Error("migrate() added onedrive settings")
+	}
+}
@@ -0,0 +1,227 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Severities lists the accepted alert severities
+var Severities = map[string]bool{"info": true, "warning": true, "error": true, "critical": true}
+
+// Problem is a single invalid setting
+type Problem struct {
+	Field   string // e.g. routing.routes[0].quiet_hours.start
+	Message string
+}
+
+// String formats a problem as "field: message"
+func (p Problem) String() string {
+	return p.Field + ": " + p.Message
+}
+
+// ValidationError holds all problems found by Validate
+type ValidationError struct {
+	Problems []Problem
+}
+
+// Error implements the error interface
+func (e *ValidationError) Error() string {
+	problems := make([]string, len(e.Problems))
+	for i, p := range e.Problems {
+		problems[i] = p.String()
+	}
+	return fmt.Sprintf("%d configuration problem(s): %s", len(e.Problems), strings.Join(problems, "; "))
+}
+
+// validator collects problems
+type validator struct {
+	problems []Problem
+}
+
+// addf records a problem with a field
+func (v *validator) addf(field, format string, args ...interface{}) {
+	v.problems = append(v.problems, Problem{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// Validate checks the whole configuration and returns a *ValidationError
+// listing every problem, or nil. It does not contact OneDrive or Telegram.
+func (c *Config) Validate() error {
+	v := &validator{}
+
+	// Single fields, as checked by Set
+	value := reflect.ValueOf(c).Elem()
+	for _, f := range configFields(value.Type(), "", envPrefix, nil) {
+		if err := validateField(f.key, value.FieldByIndex(f.index).Interface()); err != nil {
+			v.addf(f.key, "%v", err)
+		}
+	}
+
+	if c.OneDrive.AccessToken == "" {
+		v.addf("onedrive.access_token", "not logged in to OneDrive, run 'restic-backup-checker login'")
+	}
+	if len(c.OneDrive.MonitorPaths) == 0 {
+		v.addf("onedrive.monitor_paths", "no folders monitored, run 'restic-backup-checker paths add'")
+	}
+	seen := make(map[string]bool)
+	for i, p := range c.OneDrive.MonitorPaths {
+		field := fmt.Sprintf("onedrive.monitor_paths[%d]", i)
+		switch {
+		case strings.TrimSpace(p) == "":
+			v.addf(field, "empty folder ID")
+		case seen[p]:
+			v.addf(field, "%s is listed more than once", p)
+		}
+		seen[p] = true
+	}
+
+	if c.Telegram.BotToken == "" {
+		v.addf("telegram.bot_token", "required")
+	}
+	if c.Telegram.ChatID == 0 && !c.Routing.Default.HasDestinations() {
+		v.addf("telegram.chat_id", "required unless the default route has destinations")
+	}
+
+	c.validateRouting(v)
+	c.validateEscalation(v)
+
+	if c.Web.Password != "" && c.Web.Username == "" {
+		v.addf("web.username", "required when web.password is set")
+	}
+	if c.Web.Username != "" && c.Web.Password == "" {
+		v.addf("web.password", "required when web.username is set")
+	}
+
+	names := make([]string, 0, len(c.Templates))
+	for name := range c.Templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		path := c.Templates[name]
+		field := "templates." + name
+		if parts := strings.Split(name, "."); len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			v.addf(field, "expected a <type>.<channel> name")
+		}
+		if _, err := os.Stat(path); err != nil {
+			v.addf(field, "%v", err)
+		}
+	}
+
+	if len(v.problems) > 0 {
+		return &ValidationError{Problems: v.problems}
+	}
+	return nil
+}
+
+// validateRouting checks the notification routes and their destinations
+func (c *Config) validateRouting(v *validator) {
+	usesEmail := len(c.Routing.Default.Emails) > 0
+	names := make(map[string]bool)
+	for i, r := range c.Routing.Routes {
+		field := fmt.Sprintf("routing.routes[%d]", i)
+		switch {
+		case r.Name == "":
+			v.addf(field+".name", "required")
+		case r.Name == "default":
+			v.addf(field+".name", "default is reserved for the default route")
+		case names[r.Name]:
+			v.addf(field+".name", "route %s is defined more than once", r.Name)
+		}
+		names[r.Name] = true
+
+		if len(r.Paths) == 0 && len(r.Clients) == 0 {
+			v.addf(field, "needs at least one path or client matcher")
+		}
+		if !r.HasDestinations() {
+			v.addf(field, "needs at least one chat, email or webhook destination")
+		}
+		validateDestinations(v, field, r)
+		validateQuietHours(v, field+".quiet_hours", r.QuietHours)
+		usesEmail = usesEmail || len(r.Emails) > 0
+	}
+
+	validateDestinations(v, "routing.default", c.Routing.Default)
+	validateQuietHours(v, "routing.default.quiet_hours", c.Routing.Default.QuietHours)
+
+	if usesEmail {
+		if c.Email.SMTPHost == "" {
+			v.addf("email.smtp_host", "required to send to email destinations")
+		}
+		if c.Email.SMTPPort == 0 {
+			v.addf("email.smtp_port", "required to send to email destinations")
+		}
+		if c.Email.From == "" {
+			v.addf("email.from", "required to send to email destinations")
+		}
+	}
+}
+
+// validateDestinations checks the destinations of a route
+func validateDestinations(v *validator, field string, r RouteConfig) {
+	for i, id := range r.ChatIDs {
+		if id == 0 {
+			v.addf(fmt.Sprintf("%s.chat_ids[%d]", field, i), "invalid chat ID 0")
+		}
+	}
+	if err := validateField(field+".emails", r.Emails); err != nil {
+		v.addf(field+".emails", "%v", err)
+	}
+	if err := validateField(field+".webhooks", r.Webhooks); err != nil {
+		v.addf(field+".webhooks", "%v", err)
+	}
+}
+
+// validateQuietHours checks the quiet hours of a route, if set
+func validateQuietHours(v *validator, field string, q *QuietHoursConfig) {
+	if q == nil {
+		return
+	}
+	if _, err := time.Parse("15:04", q.Start); err != nil {
+		v.addf(field+".start", "expected HH:MM, got %q", q.Start)
+	}
+	if _, err := time.Parse("15:04", q.End); err != nil {
+		v.addf(field+".end", "expected HH:MM, got %q", q.End)
+	}
+	if q.TimeZone != "" {
+		if _, err := time.LoadLocation(q.TimeZone); err != nil {
+			v.addf(field+".time_zone", "%v", err)
+		}
+	}
+	if q.BreakThrough != "" && !Severities[q.BreakThrough] {
+		v.addf(field+".break_through", "expected info, warning, error or critical, got %q", q.BreakThrough)
+	}
+}
+
+// validateEscalation checks the escalation steps
+func (c *Config) validateEscalation(v *validator) {
+	routes := map[string]bool{"default": true}
+	for _, r := range c.Routing.Routes {
+		routes[r.Name] = true
+	}
+
+	for i, step := range c.Escalation {
+		field := fmt.Sprintf("escalation[%d]", i)
+		if step.AfterFailures <= 0 && step.AfterHours <= 0 {
+			v.addf(field, "needs after_failures or after_hours")
+		}
+		if step.AfterFailures < 0 {
+			v.addf(field+".after_failures", "must not be negative")
+		}
+		if step.AfterHours < 0 {
+			v.addf(field+".after_hours", "must not be negative")
+		}
+		if step.Route == "" && step.Severity == "" {
+			v.addf(field, "needs a route or severity")
+		}
+		if step.Route != "" && !routes[step.Route] {
+			v.addf(field+".route", "route %s not found", step.Route)
+		}
+		if step.Severity != "" && !Severities[step.Severity] {
+			v.addf(field+".severity", "expected info, warning, error or critical, got %q", step.Severity)
+		}
+	}
+}
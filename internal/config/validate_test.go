@@ -0,0 +1,99 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// validConfig returns a configuration that passes Validate
+func validConfig() *Config {
+	c := &Config{}
+	*c = defaults()
+	c.OneDrive.AccessToken = "token"
+	c.OneDrive.MonitorPaths = []string{"01SERVERS"}
+	c.Telegram.BotToken = "123456:ABC-DEF"
+	c.Telegram.ChatID = 1
+	return c
+}
+
+func TestValidate(t *testing.T) {
+	template := filepath.Join(t.TempDir(), "alert.tmpl")
+	if err := os.WriteFile(template, []byte("{{.Name}}"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(c *Config)
+		want   []string // fields with problems
+	}{
+		{"valid", func(c *Config) {}, nil},
+		{"not logged in", func(c *Config) { c.OneDrive.AccessToken = "" }, []string{"onedrive.access_token"}},
+		{"no folders", func(c *Config) { c.OneDrive.MonitorPaths = nil }, []string{"onedrive.monitor_paths"}},
+		{"duplicate folder", func(c *Config) { c.OneDrive.MonitorPaths = []string{"01A", "01A"} }, []string{"onedrive.monitor_paths[1]"}},
+		{"empty folder", func(c *Config) { c.OneDrive.MonitorPaths = []string{" "} }, []string{"onedrive.monitor_paths[0]"}},
+		{"invalid single field", func(c *Config) { c.Monitoring.CheckInterval = 0 }, []string{"monitoring.check_interval"}},
+		{"no bot token", func(c *Config) { c.Telegram.BotToken = "" }, []string{"telegram.bot_token"}},
+		{"no chat", func(c *Config) { c.Telegram.ChatID = 0 }, []string{"telegram.chat_id"}},
+		{"default route instead of chat", func(c *Config) {
+			c.Telegram.ChatID = 0
+			c.Routing.Default.ChatIDs = []int64{1}
+		}, nil},
+		{"route problems", func(c *Config) {
+			c.Routing.Routes = []RouteConfig{
+				{Name: "default", Clients: []string{"a"}, ChatIDs: []int64{1}},
+				{Name: "ops", ChatIDs: []int64{0}},
+				{Name: "ops", Paths: []string{"b"}, QuietHours: &QuietHoursConfig{Start: "25:00", End: "07:00", BreakThrough: "loud"}},
+			}
+		}, []string{
+			"routing.routes[0].name",
+			"routing.routes[1]",
+			"routing.routes[1].chat_ids[0]",
+			"routing.routes[2].name",
+			"routing.routes[2]",
+			"routing.routes[2].quiet_hours.start",
+			"routing.routes[2].quiet_hours.break_through",
+		}},
+		{"email without SMTP", func(c *Config) { c.Routing.Default.Emails = []string{"ops@example.com"} }, []string{"email.smtp_host", "email.smtp_port", "email.from"}},
+		{"escalation problems", func(c *Config) {
+			c.Escalation = []EscalationStep{
+				{AfterFailures: 3, Route: "admins"},
+				{AfterHours: -1, Severity: "loud"},
+			}
+		}, []string{
+			"escalation[0].route",
+			"escalation[1]",
+			"escalation[1].after_hours",
+			"escalation[1].severity",
+		}},
+		{"web password without username", func(c *Config) { c.Web.Password = "secret" }, []string{"web.username"}},
+		{"template", func(c *Config) { c.Templates = map[string]string{"alert.telegram": template} }, nil},
+		{"template problems", func(c *Config) {
+			c.Templates = map[string]string{"alert": template, "alert.email": filepath.Join(t.TempDir(), "missing")}
+		}, []string{"templates.alert", "templates.alert.email"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := validConfig()
+			tt.modify(c)
+
+			err := c.Validate()
+			var got []string
+			var verr *ValidationError
+			if errors.As(err, &verr) {
+				for _, p := range verr.Problems {
+					got = append(got, p.Field)
+				}
+			} else if err != nil {
+				t.Fatalf("Validate() = %v, want a *ValidationError", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate() problems = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package email
+
+import (
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Client sends plain-text notification emails over SMTP
+type Client struct {
+	host     string
+	port     int
+	username string
+	password string
+	from     string
+}
+
+// New creates a new SMTP client. Authentication is skipped if username is empty.
+func New(host string, port int, username, password, from string) *Client {
+	if port == 0 {
+		port = 587
+	}
+
+	return &Client{
+		host:     host,
+		port:     port,
+		username: username,
+		password: password,
+		from:     from,
+	}
+}
+
+// Name returns the channel name used in logs
+func (c *Client) Name() string {
+	return "email"
+}
+
+// Send sends a plain-text message to the given recipients
+func (c *Client) Send(to []string, subject, body string) error {
+	if len(to) == 0 {
+		return nil
+	}
+
+	var auth smtp.Auth
+	if c.username != "" {
+		auth = smtp.PlainAuth("", c.username, c.password, c.host)
+	}
+
+	addr := net.JoinHostPort(c.host, strconv.Itoa(c.port))
+	if err := smtp.SendMail(addr, auth, c.from, to, c.message(to, subject, body)); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	return nil
+}
+
+// message builds an RFC 5322 message with CRLF line endings
+func (c *Client) message(to []string, subject, body string) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", c.from)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", strings.NewReplacer("\r", "", "\n", " ").Replace(subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(body, "\r\n", "\n"), "\n", "\r\n"))
+	b.WriteString("\r\n")
+	return []byte(b.String())
+}
@@ -0,0 +1,82 @@
+package heartbeat
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Disabled can be given as the start or fail URL to skip that ping
+const Disabled = "none"
+
+// Client pings a dead man's switch such as healthchecks.io or an Uptime Kuma
+// push monitor, so an external watcher notices when the checker goes silent
+type Client struct {
+	url        string
+	startURL   string
+	failURL    string
+	httpClient *http.Client
+}
+
+// New creates a heartbeat client. url is pinged after a successful check run;
+// startURL and failURL default to url + "/start" and url + "/fail".
+//
+// URLs may contain the placeholders {duration} and {duration_ms}, replaced
+// with the run duration in seconds and milliseconds.
+func New(url, startURL, failURL string) *Client {
+	url = strings.TrimRight(url, "/")
+	if startURL == "" {
+		startURL = url + "/start"
+	}
+	if failURL == "" {
+		failURL = url + "/fail"
+	}
+
+	return &Client{
+		url:        url,
+		startURL:   startURL,
+		failURL:    failURL,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Start signals that a check run has started
+func (c *Client) Start() error {
+	return c.ping(c.startURL, 0, "")
+}
+
+// Success signals that a check run completed, with a plain-text run report
+func (c *Client) Success(duration time.Duration, report string) error {
+	return c.ping(c.url, duration, report)
+}
+
+// Fail signals that a check run failed, with a plain-text run report
+func (c *Client) Fail(duration time.Duration, report string) error {
+	return c.ping(c.failURL, duration, report)
+}
+
+// ping posts the report to a heartbeat URL
+func (c *Client) ping(url string, duration time.Duration, report string) error {
+	if url == Disabled {
+		return nil
+	}
+
+	url = strings.NewReplacer(
+		"{duration}", strconv.FormatFloat(duration.Seconds(), 'f', 3, 64),
+		"{duration_ms}", strconv.FormatInt(duration.Milliseconds(), 10),
+	).Replace(url)
+
+	resp, err := c.httpClient.Post(url, "text/plain; charset=utf-8", strings.NewReader(report))
+	if err != nil {
+		return fmt.Errorf("failed to ping heartbeat: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("heartbeat ping failed with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package heartbeat
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder records the pings a test server receives
+type recorder struct {
+	mu     sync.Mutex
+	pings  []string // request URIs
+	bodies []string
+	status int
+}
+
+func (r *recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pings = append(r.pings, req.URL.RequestURI())
+	r.bodies = append(r.bodies, string(body))
+	if r.status != 0 {
+		w.WriteHeader(r.status)
+	}
+}
+
+func TestPings(t *testing.T) {
+	tests := []struct {
+		name     string
+		startURL string
+		failURL  string
+		ping     func(c *Client) error
+		want     []string
+	}{
+		{"start", "", "", func(c *Client) error { return c.Start() }, []string{"/ping/start"}},
+		{"success", "", "", func(c *Client) error { return c.Success(1500*time.Millisecond, "ok") }, []string{"/ping"}},
+		{"fail", "", "", func(c *Client) error { return c.Fail(time.Second, "failed") }, []string{"/ping/fail"}},
+		{"start disabled", Disabled, "", func(c *Client) error { return c.Start() }, nil},
+		{"fail disabled", "", Disabled, func(c *Client) error { return c.Fail(time.Second, "failed") }, nil},
+		{"custom start", "{base}/begin", "", func(c *Client) error { return c.Start() }, []string{"/begin"}},
+		{"duration placeholders", "", "{base}/failed?s={duration}&ms={duration_ms}", func(c *Client) error { return c.Fail(1500*time.Millisecond, "") }, []string{"/failed?s=1.500&ms=1500"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &recorder{}
+			server := httptest.NewServer(rec)
+			defer server.Close()
+
+			base := func(u string) string { return strings.ReplaceAll(u, "{base}", server.URL) }
+			c := New(server.URL+"/ping/", base(tt.startURL), base(tt.failURL))
+			if err := tt.ping(c); err != nil {
+				t.Fatal(err)
+			}
+
+			if strings.Join(rec.pings, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("pings = %v, want %v", rec.pings, tt.want)
+			}
+		})
+	}
+}
+
+func TestPingReport(t *testing.T) {
+	rec := &recorder{}
+	server := httptest.NewServer(rec)
+	defer server.Close()
+
+	if err := New(server.URL, "", "").Success(time.Second, "clients_total=3\n"); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.bodies) != 1 || rec.bodies[0] != "clients_total=3\n" {
+		t.Errorf("bodies = %q", rec.bodies)
+	}
+}
+
+func TestPingErrorStatus(t *testing.T) {
+	rec := &recorder{status: http.StatusNotFound}
+	server := httptest.NewServer(rec)
+	defer server.Close()
+
+	if err := New(server.URL, "", "").Start(); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("Start() error = %v, want one naming status 404", err)
+	}
+}
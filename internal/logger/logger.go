@@ -1,33 +1,200 @@
+// Package logger writes levelled log messages through log/slog, as text or
+// JSON, to stderr or a rotated log file. Messages are printf-style; loggers
+// returned by With additionally attach structured fields such as client,
+// folder_id, run_id or duration.
 package logger
 
 import (
+	"context"
+	"fmt"
+	"io"
 	"log"
+	"log/slog"
 	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// Output formats
+const (
+	FormatText = "text"
+	FormatJSON = "json"
 )
 
-// Init initializes the logger
+// Options configures the logger
+type Options struct {
+	Level      string // debug, info, warn or error
+	Format     string // text or json
+	File       string // log file, stderr if empty
+	MaxSizeMB  int    // rotate the log file once it reaches this size
+	MaxAgeDays int    // delete rotated files older than this, 0 keeps them
+	MaxBackups int    // number of rotated files kept, 0 keeps all
+}
+
+// Logger writes log messages with a fixed set of structured fields
+type Logger struct {
+	handler slog.Handler
+}
+
+var (
+	level = new(slog.LevelVar)
+	std   = &Logger{handler: newHandler(os.Stderr, FormatText)}
+)
+
+// Init initializes the logger with the default options: info level, text
+// output to stderr
 func Init() {
-	log.SetFlags(log.LstdFlags | log.Lshortfile)
-	log.SetPrefix("[restic-backup-checker] ")
+	if err := Configure(Options{}); err != nil {
+		log.Fatalf("Failed to initialize logger: %v", err)
+	}
+}
+
+// Configure applies options to the logger. Messages written through the
+// standard log package are routed through it as well.
+func Configure(opts Options) error {
+	if err := SetLevel(opts.Level); err != nil {
+		return err
+	}
+
+	format := strings.ToLower(opts.Format)
+	switch format {
+	case "":
+		format = FormatText
+	case FormatText, FormatJSON:
+	default:
+		return fmt.Errorf("invalid log format %q, expected text or json", opts.Format)
+	}
+
+	var out io.Writer = os.Stderr
+	if opts.File != "" {
+		if err := os.MkdirAll(filepath.Dir(opts.File), 0700); err != nil {
+			return fmt.Errorf("failed to create log directory: %w", err)
+		}
+		out = &lumberjack.Logger{
+			Filename:   opts.File,
+			MaxSize:    opts.MaxSizeMB, // lumberjack defaults to 100 MB if zero
+			MaxAge:     opts.MaxAgeDays,
+			MaxBackups: opts.MaxBackups,
+		}
+	}
+
+	std = &Logger{handler: newHandler(out, format)}
+	slog.SetDefault(slog.New(std.handler))
+	return nil
+}
+
+// SetLevel sets the minimum level of messages written; empty means info
+func SetLevel(name string) error {
+	switch strings.ToLower(name) {
+	case "debug":
+		level.Set(slog.LevelDebug)
+	case "", "info":
+		level.Set(slog.LevelInfo)
+	case "warn", "warning":
+		level.Set(slog.LevelWarn)
+	case "error":
+		level.Set(slog.LevelError)
+	default:
+		return fmt.Errorf("invalid log level %q, expected debug, info, warn or error", name)
+	}
+	return nil
+}
+
+// newHandler creates a slog handler writing to out in the given format
+func newHandler(out io.Writer, format string) slog.Handler {
+	opts := &slog.HandlerOptions{
+		AddSource:   true,
+		Level:       level,
+		ReplaceAttr: shortSource,
+	}
+	if format == FormatJSON {
+		return slog.NewJSONHandler(out, opts)
+	}
+	return slog.NewTextHandler(out, opts)
+}
+
+// shortSource reduces the source attribute to file:line
+func shortSource(groups []string, a slog.Attr) slog.Attr {
+	if a.Key != slog.SourceKey {
+		return a
+	}
+	if src, ok := a.Value.Any().(*slog.Source); ok {
+		a.Value = slog.StringValue(fmt.Sprintf("%s:%d", filepath.Base(src.File), src.Line))
+	}
+	return a
+}
+
+// With returns a logger adding the given key-value pairs to every message
+func With(args ...interface{}) *Logger {
+	return std.With(args...)
+}
+
+// With returns a logger adding the given key-value pairs to every message
+func (l *Logger) With(args ...interface{}) *Logger {
+	return &Logger{handler: slog.New(l.handler).With(args...).Handler()}
+}
+
+// Debug logs a debug message
+func (l *Logger) Debug(format string, args ...interface{}) {
+	l.log(slog.LevelDebug, format, args...)
 }
 
 // Info logs an info message
-func Info(format string, args ...interface{}) {
-	log.Printf("INFO: "+format, args...)
+func (l *Logger) Info(format string, args ...interface{}) {
+	l.log(slog.LevelInfo, format, args...)
+}
+
+// Warn logs a warning
+func (l *Logger) Warn(format string, args ...interface{}) {
+	l.log(slog.LevelWarn, format, args...)
 }
 
 // Error logs an error message
-func Error(format string, args ...interface{}) {
-	log.Printf("ERROR: "+format, args...)
+func (l *Logger) Error(format string, args ...interface{}) {
+	l.log(slog.LevelError, format, args...)
 }
 
-// Fatal logs a fatal error and exits
-func Fatal(format string, args ...interface{}) {
-	log.Printf("FATAL: "+format, args...)
-	os.Exit(1)
+// log writes a message attributed to the caller of the logging function
+func (l *Logger) log(lvl slog.Level, format string, args ...interface{}) {
+	ctx := context.Background()
+	if !l.handler.Enabled(ctx, lvl) {
+		return
+	}
+
+	// Skip runtime.Callers, log and the logging function
+	var pcs [1]uintptr
+	runtime.Callers(3, pcs[:])
+
+	record := slog.NewRecord(time.Now(), lvl, fmt.Sprintf(format, args...), pcs[0])
+	_ = l.handler.Handle(ctx, record)
 }
 
 // Debug logs a debug message
 func Debug(format string, args ...interface{}) {
-	log.Printf("DEBUG: "+format, args...)
+	std.log(slog.LevelDebug, format, args...)
+}
+
+// Info logs an info message
+func Info(format string, args ...interface{}) {
+	std.log(slog.LevelInfo, format, args...)
+}
+
+// Warn logs a warning
+func Warn(format string, args ...interface{}) {
+	std.log(slog.LevelWarn, format, args...)
+}
+
+// Error logs an error message
+func Error(format string, args ...interface{}) {
+	std.log(slog.LevelError, format, args...)
+}
+
+// Fatal logs an error message and exits
+func Fatal(format string, args ...interface{}) {
+	std.log(slog.LevelError, format, args...)
+	os.Exit(1)
 }
@@ -0,0 +1,76 @@
+package logger
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfigureInvalid(t *testing.T) {
+	t.Cleanup(Init)
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{"level", Options{Level: "verbose"}},
+		{"format", Options{Format: "yaml"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Configure(tt.opts); err == nil {
+				t.Error("Configure() accepted invalid options")
+			}
+		})
+	}
+}
+
+func TestJSONLogFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "logs", "checker.log")
+	if err := Configure(Options{Level: "WARNING", Format: "JSON", File: file}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(Init)
+
+	log := With("run_id", "abc123")
+	log.Info("not written at warn level")
+	log.With("client", "web01").Warn("client %s failed", "web01")
+	Error("check failed")
+
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var records []map[string]interface{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var record map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("invalid JSON line %q: %v", scanner.Text(), err)
+		}
+		records = append(records, record)
+	}
+
+	if len(records) != 2 {
+		t.Fatalf("got %d records, want 2: %v", len(records), records)
+	}
+	want := []map[string]string{
+		{"level": "WARN", "msg": "client web01 failed", "run_id": "abc123", "client": "web01"},
+		{"level": "ERROR", "msg": "check failed"},
+	}
+	for i, fields := range want {
+		for key, value := range fields {
+			if records[i][key] != value {
+				t.Errorf("record %d %s = %v, want %q", i, key, records[i][key], value)
+			}
+		}
+		// The source is the caller, not the logger
+		if source, _ := records[i]["source"].(string); !strings.HasPrefix(source, "logger_test.go:") {
+			t.Errorf("record %d source = %q", i, source)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+// Package metrics exposes check results to Prometheus
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"restic-backup-checker/internal/templates"
+)
+
+const namespace = "restic_backup_checker"
+
+// statusCodes maps client statuses to the value of the client_status gauge
+var statusCodes = map[string]float64{
+	templates.StatusOK:          0,
+	templates.StatusStale:       1,
+	templates.StatusNoSnapshots: 2,
+	templates.StatusNotARepo:    3,
+	templates.StatusCheckError:  4,
+	templates.StatusAuthError:   5,
+}
+
+// Metrics holds the collectors updated by the monitor
+type Metrics struct {
+	registry *prometheus.Registry
+
+	lastSnapshot    *prometheus.GaugeVec
+	snapshots       *prometheus.GaugeVec
+	recentSnapshots *prometheus.GaugeVec
+	clientStatus    *prometheus.GaugeVec
+	checkDuration   prometheus.Histogram
+	lastRun         prometheus.Gauge
+	graphRequests   *prometheus.CounterVec
+	tokenExpiry     *prometheus.GaugeVec
+	sendFailures    *prometheus.CounterVec
+}
+
+// Client holds the per-client values of a check run
+type Client struct {
+	Name            string
+	MonitorPath     string
+	Status          string
+	LastSnapshot    time.Time
+	Snapshots       int
+	RecentSnapshots int
+}
+
+// New creates the collectors and registers them with a new registry
+func New() *Metrics {
+	clientLabels := []string{"client", "monitor_path"}
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		lastSnapshot: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "last_snapshot_timestamp_seconds",
+			Help:      "Creation time of the newest snapshot of a client, 0 if none.",
+		}, clientLabels),
+		snapshots: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "snapshots",
+			Help:      "Number of snapshot files of a client.",
+		}, clientLabels),
+		recentSnapshots: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "recent_snapshots",
+			Help:      "Number of snapshot files of a client created in the last 24 hours.",
+		}, clientLabels),
+		clientStatus: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "client_status",
+			Help:      "Status of a client: 0 OK, 1 STALE, 2 NO_SNAPSHOTS, 3 NOT_A_REPO, 4 CHECK_ERROR, 5 AUTH_ERROR.",
+		}, clientLabels),
+		checkDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "check_duration_seconds",
+			Help:      "Duration of check runs.",
+			Buckets:   []float64{1, 2.5, 5, 10, 30, 60, 120, 300, 600},
+		}),
+		lastRun: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "last_check_timestamp_seconds",
+			Help:      "Completion time of the last check run.",
+		}),
+		graphRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "graph_requests_total",
+			Help:      "Microsoft Graph API requests by HTTP status code, \"error\" if no response was received.",
+		}, []string{"code"}),
+		tokenExpiry: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "token_expiry_timestamp_seconds",
+			Help:      "Expiry time of the OneDrive access token and the expected expiry of the refresh token.",
+		}, []string{"token"}),
+		sendFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "notification_failures_total",
+			Help:      "Notifications that could not be sent, by channel.",
+		}, []string{"channel"}),
+	}
+
+	m.registry.MustRegister(
+		m.lastSnapshot, m.snapshots, m.recentSnapshots, m.clientStatus,
+		m.checkDuration, m.lastRun, m.graphRequests, m.tokenExpiry, m.sendFailures,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	return m
+}
+
+// ObserveRun records the results of a completed check run. Clients no longer
+// present are dropped.
+func (m *Metrics) ObserveRun(duration time.Duration, clients []Client) {
+	m.checkDuration.Observe(duration.Seconds())
+	m.lastRun.SetToCurrentTime()
+
+	m.lastSnapshot.Reset()
+	m.snapshots.Reset()
+	m.recentSnapshots.Reset()
+	m.clientStatus.Reset()
+	for _, c := range clients {
+		labels := prometheus.Labels{"client": c.Name, "monitor_path": c.MonitorPath}
+		var lastSnapshot float64
+		if !c.LastSnapshot.IsZero() {
+			lastSnapshot = float64(c.LastSnapshot.Unix())
+		}
+		m.lastSnapshot.With(labels).Set(lastSnapshot)
+		m.snapshots.With(labels).Set(float64(c.Snapshots))
+		m.recentSnapshots.With(labels).Set(float64(c.RecentSnapshots))
+		code, ok := statusCodes[c.Status]
+		if !ok {
+			code = statusCodes[templates.StatusCheckError]
+		}
+		m.clientStatus.With(labels).Set(code)
+	}
+}
+
+// GraphRequest counts a Graph API request by its response code
+func (m *Metrics) GraphRequest(code string) {
+	m.graphRequests.WithLabelValues(code).Inc()
+}
+
+// SetTokenExpiry records the expiry of the access token and the expected
+// expiry of the refresh token; zero times are not exported
+func (m *Metrics) SetTokenExpiry(accessToken, refreshToken time.Time) {
+	for token, t := range map[string]time.Time{"access": accessToken, "refresh": refreshToken} {
+		if t.IsZero() {
+			m.tokenExpiry.DeleteLabelValues(token)
+			continue
+		}
+		m.tokenExpiry.WithLabelValues(token).Set(float64(t.Unix()))
+	}
+}
+
+// SendFailed counts a notification that could not be sent via channel
+func (m *Metrics) SendFailed(channel string) {
+	m.sendFailures.WithLabelValues(channel).Inc()
+}
+
+// Handler returns the HTTP handler serving the metrics
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// Serve exposes /metrics on addr until stop is closed
+func (m *Metrics) Serve(addr string, stop <-chan struct{}) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", m.Handler())
+
+	server := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-stop
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(ctx)
+	}()
+
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("failed to serve metrics: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"restic-backup-checker/internal/templates"
+)
+
+// scrape returns the metrics served by m
+func scrape(t *testing.T, m *Metrics) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	return rec.Body.String()
+}
+
+func TestObserveRun(t *testing.T) {
+	m := New()
+	last := time.Unix(1700000000, 0)
+	clients := []Client{
+		{Name: "web01", MonitorPath: "F1", Status: templates.StatusOK, LastSnapshot: last, Snapshots: 10, RecentSnapshots: 2},
+		{Name: "db01", MonitorPath: "F1", Status: templates.StatusNoSnapshots},
+		{Name: "web01", MonitorPath: "F2", Status: templates.StatusOK},
+	}
+	m.ObserveRun(3*time.Second, clients)
+	// A client gone from the next run is dropped
+	m.ObserveRun(2*time.Second, clients[:2])
+
+	out := scrape(t, m)
+	tests := []struct {
+		line string
+		want bool
+	}{
+		{`restic_backup_checker_last_snapshot_timestamp_seconds{client="web01",monitor_path="F1"} 1.7e+09`, true},
+		{`restic_backup_checker_last_snapshot_timestamp_seconds{client="db01",monitor_path="F1"} 0`, true},
+		{`restic_backup_checker_snapshots{client="web01",monitor_path="F1"} 10`, true},
+		{`restic_backup_checker_recent_snapshots{client="web01",monitor_path="F1"} 2`, true},
+		{`restic_backup_checker_client_status{client="web01",monitor_path="F1"} 0`, true},
+		{`restic_backup_checker_client_status{client="db01",monitor_path="F1"} 2`, true},
+		{`monitor_path="F2"`, false},
+		{`restic_backup_checker_check_duration_seconds_count 2`, true},
+	}
+	for _, tt := range tests {
+		if got := strings.Contains(out, tt.line); got != tt.want {
+			t.Errorf("metrics contain %s = %v, want %v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestUnknownStatusIsCheckError(t *testing.T) {
+	m := New()
+	m.ObserveRun(time.Second, []Client{{Name: "web01", MonitorPath: "F1", Status: "SOMETHING_NEW"}})
+	if want := `restic_backup_checker_client_status{client="web01",monitor_path="F1"} 4`; !strings.Contains(scrape(t, m), want) {
+		t.Errorf("metrics do not contain %s", want)
+	}
+}
+
+func TestCounters(t *testing.T) {
+	m := New()
+	m.GraphRequest("200")
+	m.GraphRequest("200")
+	m.GraphRequest("error")
+	m.SendFailed("email")
+	m.SetTokenExpiry(time.Unix(1700000000, 0), time.Time{})
+
+	out := scrape(t, m)
+	for _, line := range []string{
+		`restic_backup_checker_graph_requests_total{code="200"} 2`,
+		`restic_backup_checker_graph_requests_total{code="error"} 1`,
+		`restic_backup_checker_notification_failures_total{channel="email"} 1`,
+		`restic_backup_checker_token_expiry_timestamp_seconds{token="access"} 1.7e+09`,
+	} {
+		if !strings.Contains(out, line) {
+			t.Errorf("metrics do not contain %s", line)
+		}
+	}
+	if strings.Contains(out, `token="refresh"`) {
+		t.Error("unknown refresh token expiry exported")
+	}
+}
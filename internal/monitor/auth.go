@@ -0,0 +1,167 @@
+package monitor
+
+import (
+	"errors"
+	"time"
+
+	"restic-backup-checker/internal/onedrive"
+	"restic-backup-checker/internal/templates"
+)
+
+const (
+	// authIncidentKey identifies the paging incident opened while OneDrive is inaccessible
+	authIncidentKey = "restic-backup-checker/auth"
+
+	// networkFailureThreshold is the number of failed refreshes in a row
+	// before network errors are reported, so short outages stay quiet
+	networkFailureThreshold = 3
+
+	// expiryReminderWindow is how long before the expected refresh token
+	// expiry a reminder to log in again is sent, at most once a day
+	expiryReminderWindow = 14 * 24 * time.Hour
+)
+
+// authError classifies a token refresh failure. Only errors of the token
+// endpoint ask for a new login; local failures, e.g. to lock or save the
+// config, are reported like network errors.
+func authError(err error) *onedrive.AuthError {
+	var authErr *onedrive.AuthError
+	if errors.As(err, &authErr) {
+		return authErr
+	}
+	return &onedrive.AuthError{Kind: onedrive.AuthUnknown, Err: err}
+}
+
+// reportAuthFailure records a failed token refresh and notifies the default
+// route once per failure kind that monitoring is blind. Notifications are
+// sent without holding the monitor mutex; auth state is only changed by
+// checks, which are serialised.
+func (m *Monitor) reportAuthFailure(err error) {
+	authErr := authError(err)
+	now := time.Now()
+
+	m.mu.Lock()
+	auth := &m.state.Auth
+	if auth.ConsecutiveFailures == 0 {
+		auth.FailingSince = now
+	}
+	auth.ConsecutiveFailures++
+	if auth.FailureKind != string(authErr.Kind) {
+		auth.FailureKind = string(authErr.Kind)
+		auth.Notified = false
+	}
+	notify := !auth.Notified && (authErr.NeedsLogin() || auth.ConsecutiveFailures >= networkFailureThreshold)
+	data := templates.AuthData{
+		Event:       templates.AuthFailure,
+		Kind:        auth.FailureKind,
+		NeedsLogin:  authErr.NeedsLogin(),
+		Error:       authErr.Error(),
+		Since:       auth.FailingSince,
+		Failures:    auth.ConsecutiveFailures,
+		TokenExpiry: m.refreshTokenExpiry(),
+		Now:         now,
+	}
+	route := m.defaultRoute()
+	m.mu.Unlock()
+
+	notified := false
+	if notify {
+		delivered := m.deliver(route, templates.TypeAuth, data, "") == nil
+		notified = m.sendIncident(authIncidentKey, func(ch incidentChannel) error {
+			summary, err := m.templates.Render(templates.TypeAuth, ch.Name(), data)
+			if err != nil {
+				return err
+			}
+			return ch.Trigger(authIncidentKey, summary, "critical", map[string]string{
+				"kind":  data.Kind,
+				"error": data.Error,
+			})
+		}) && delivered
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if notified {
+		m.state.Auth.Notified = true
+	}
+	m.saveState()
+}
+
+// reportAuthSuccess clears a recorded authentication failure and tells the
+// default route that monitoring works again if the failure was reported
+func (m *Monitor) reportAuthSuccess() {
+	m.mu.Lock()
+	auth := m.state.Auth
+	route := m.defaultRoute()
+	m.mu.Unlock()
+
+	if auth.ConsecutiveFailures == 0 {
+		return
+	}
+
+	if auth.Notified {
+		data := templates.AuthData{
+			Event:    templates.AuthRecovered,
+			Kind:     auth.FailureKind,
+			Since:    auth.FailingSince,
+			Failures: auth.ConsecutiveFailures,
+			Now:      time.Now(),
+		}
+		if err := m.deliver(route, templates.TypeAuth, data, ""); err != nil {
+			m.log.Error("Failed to send monitoring restored notice: %v", err)
+		}
+		m.sendIncident(authIncidentKey, func(ch incidentChannel) error {
+			return ch.Resolve(authIncidentKey)
+		})
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.state.Auth.FailureKind = ""
+	m.state.Auth.FailingSince = time.Time{}
+	m.state.Auth.ConsecutiveFailures = 0
+	m.state.Auth.Notified = false
+	m.saveState()
+}
+
+// remindTokenExpiry reminds the default route to log in again when the
+// refresh token is about to expire
+func (m *Monitor) remindTokenExpiry() {
+	expiry := m.refreshTokenExpiry()
+	now := time.Now()
+	if expiry.IsZero() || expiry.Sub(now) > expiryReminderWindow {
+		return
+	}
+
+	m.mu.Lock()
+	reminded := now.Sub(m.state.Auth.ReminderSent) < 24*time.Hour
+	route := m.defaultRoute()
+	m.mu.Unlock()
+	if reminded {
+		return
+	}
+
+	data := templates.AuthData{
+		Event:       templates.AuthExpiring,
+		TokenExpiry: expiry,
+		Now:         now,
+	}
+	if err := m.deliver(route, templates.TypeAuth, data, ""); err != nil {
+		m.log.Error("Failed to send login expiry reminder: %v", err)
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.state.Auth.ReminderSent = now
+	m.saveState()
+}
+
+// refreshTokenExpiry returns when the refresh token is expected to expire,
+// or zero if it is unknown
+func (m *Monitor) refreshTokenExpiry() time.Time {
+	if m.config.OneDrive.RefreshTokenIssued == 0 {
+		return time.Time{}
+	}
+	return time.Unix(m.config.OneDrive.RefreshTokenIssued, 0).Add(onedrive.RefreshTokenLifetime)
+}
@@ -0,0 +1,79 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"restic-backup-checker/internal/config"
+	"restic-backup-checker/internal/logger"
+	"restic-backup-checker/internal/onedrive"
+	"restic-backup-checker/internal/state"
+	"restic-backup-checker/internal/templates"
+)
+
+// testMonitor returns a monitor without notification channels, keeping its
+// state in a temporary directory
+func testMonitor(t *testing.T, cfg *config.Config) *Monitor {
+	t.Helper()
+	store, err := state.Load(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Monitor{config: cfg, state: store, templates: templates.Default(), log: logger.With()}
+}
+
+func TestAuthError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want onedrive.AuthErrorKind
+	}{
+		{"invalid grant", &onedrive.AuthError{Kind: onedrive.AuthInvalidGrant}, onedrive.AuthInvalidGrant},
+		{"wrapped consent", fmt.Errorf("failed to refresh token: %w", &onedrive.AuthError{Kind: onedrive.AuthConsentRequired}), onedrive.AuthConsentRequired},
+		{"network", &onedrive.AuthError{Kind: onedrive.AuthNetwork}, onedrive.AuthNetwork},
+		{"lock timeout", errors.New("failed to lock configuration: config file is locked by another process"), onedrive.AuthUnknown},
+		{"save failure", fmt.Errorf("failed to save refreshed token: %w", errors.New("disk full")), onedrive.AuthUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := authError(tt.err).Kind; got != tt.want {
+				t.Errorf("authError() kind = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReportAuthFailure(t *testing.T) {
+	tests := []struct {
+		name         string
+		err          error
+		wantNotified []bool // after each failed refresh
+	}{
+		{"invalid grant", &onedrive.AuthError{Kind: onedrive.AuthInvalidGrant}, []bool{true, true}},
+		{"network", &onedrive.AuthError{Kind: onedrive.AuthNetwork}, []bool{false, false, true}},
+		{"local failure", errors.New("failed to lock configuration"), []bool{false, false, true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := testMonitor(t, &config.Config{})
+			for i, want := range tt.wantNotified {
+				m.reportAuthFailure(tt.err)
+				if got := m.state.Auth.Notified; got != want {
+					t.Errorf("notified after failure %d = %v, want %v", i+1, got, want)
+				}
+			}
+			if m.state.Auth.ConsecutiveFailures != len(tt.wantNotified) {
+				t.Errorf("ConsecutiveFailures = %d", m.state.Auth.ConsecutiveFailures)
+			}
+
+			m.reportAuthSuccess()
+			if m.state.Auth.ConsecutiveFailures != 0 || m.state.Auth.Notified || m.state.Auth.FailureKind != "" {
+				t.Errorf("auth state after success = %+v", m.state.Auth)
+			}
+		})
+	}
+}
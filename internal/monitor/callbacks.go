@@ -0,0 +1,79 @@
+package monitor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"restic-backup-checker/internal/state"
+	"restic-backup-checker/internal/telegram"
+)
+
+// snoozeDuration is how long the Snooze button silences a client
+const snoozeDuration = 4 * time.Hour
+
+// actionKey returns a short identifier of a client that fits in Telegram's
+// 64-byte callback data
+func actionKey(monitorPath, clientName string) string {
+	sum := sha256.Sum256([]byte(state.ClientKey(monitorPath, clientName)))
+	return hex.EncodeToString(sum[:8])
+}
+
+// clientByActionKey finds the client an alert button in a chat refers to,
+// among the clients the chat may see. Callers must hold m.mu.
+func (m *Monitor) clientByActionKey(chatID int64, key string) *state.ClientState {
+	for _, cs := range m.visibleClients(chatID, m.state.List()) {
+		if actionKey(cs.MonitorPath, cs.ClientName) == key {
+			return cs
+		}
+	}
+	return nil
+}
+
+// handleCallback handles a press of an alert button
+func (m *Monitor) handleCallback(cb telegram.Callback) telegram.CallbackResult {
+	if cb.Action == telegram.ActionRecheck {
+		m.log.Info("Re-check requested by %s", cb.From)
+		m.goSafe("Requested backup check", func() {
+			if err := m.CheckOnce(); err != nil {
+				m.log.Error("Requested backup check failed: %v", err)
+			}
+		})
+		return telegram.CallbackResult{Notice: "🔄 Re-checking backups..."}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cs := m.clientByActionKey(cb.ChatID, cb.Key)
+	if cs == nil {
+		return telegram.CallbackResult{Notice: "Unknown client"}
+	}
+
+	now := time.Now()
+	switch cb.Action {
+	case telegram.ActionAcknowledge:
+		cs.AckedBy = cb.From
+		cs.AckedAt = now
+		m.saveState()
+
+		m.log.Info("Alert for client %s acknowledged by %s", cs.Name(), cb.From)
+		return telegram.CallbackResult{
+			Notice: "Acknowledged",
+			Note:   fmt.Sprintf("✅ Acknowledged by %s at %s", cb.From, formatTime(now)),
+		}
+	case telegram.ActionSnooze:
+		cs.SilencedUntil = now.Add(snoozeDuration)
+		m.saveState()
+
+		m.log.Info("Client %s snoozed until %s by %s", cs.Name(), formatTime(cs.SilencedUntil), cb.From)
+		return telegram.CallbackResult{
+			Notice:     "Snoozed for 4 hours",
+			Note:       fmt.Sprintf("🔕 Snoozed until %s by %s", formatTime(cs.SilencedUntil), cb.From),
+			KeepButton: true,
+		}
+	default:
+		return telegram.CallbackResult{Notice: "Unknown action"}
+	}
+}
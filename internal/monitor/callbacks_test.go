@@ -0,0 +1,68 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+
+	"restic-backup-checker/internal/config"
+	"restic-backup-checker/internal/telegram"
+)
+
+func TestHandleCallback(t *testing.T) {
+	key := actionKey("F1", "web01")
+
+	tests := []struct {
+		name       string
+		chatID     int64
+		action     string
+		key        string
+		wantNotice string
+		wantAcked  bool
+		wantSnooze bool
+	}{
+		{"acknowledge", 1, telegram.ActionAcknowledge, key, "Acknowledged", true, false},
+		{"snooze", 1, telegram.ActionSnooze, key, "Snoozed for 4 hours", false, true},
+		{"unknown action", 1, "delete", key, "Unknown action", false, false},
+		{"unknown client", 1, telegram.ActionAcknowledge, actionKey("F1", "db01"), "Unknown client", false, false},
+		{"client routed elsewhere", 2, telegram.ActionAcknowledge, key, "Unknown client", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{}
+			cfg.Telegram.ChatID = 1
+			cfg.Routing.Routes = []config.RouteConfig{{Name: "db", Clients: []string{"db*"}, ChatIDs: []int64{2}}}
+			m := testMonitor(t, cfg)
+			m.state.Client("F1", "web01")
+
+			result := m.handleCallback(telegram.Callback{ChatID: tt.chatID, From: "alice", Action: tt.action, Key: tt.key})
+			if result.Notice != tt.wantNotice {
+				t.Errorf("Notice = %q, want %q", result.Notice, tt.wantNotice)
+			}
+			if (result.Note != "") != (tt.wantAcked || tt.wantSnooze) {
+				t.Errorf("Note = %q", result.Note)
+			}
+
+			cs := m.state.Get("F1", "web01")
+			if acked := cs.AckedBy == "alice"; acked != tt.wantAcked {
+				t.Errorf("AckedBy = %q", cs.AckedBy)
+			}
+			if snoozed := cs.SilencedUntil.After(time.Now()); snoozed != tt.wantSnooze {
+				t.Errorf("SilencedUntil = %v", cs.SilencedUntil)
+			}
+			if result.KeepButton != tt.wantSnooze {
+				t.Errorf("KeepButton = %v", result.KeepButton)
+			}
+		})
+	}
+}
+
+func TestActionKeyFitsCallbackData(t *testing.T) {
+	key := actionKey("01ABCDEF2GHIJKLMNOPQRSTUVWXYZ", "a-client-with-a-rather-long-name-indeed")
+	if len(telegram.ActionAcknowledge+":"+key) > 64 {
+		t.Errorf("callback data %q exceeds 64 bytes", key)
+	}
+	if key == actionKey("01ABCDEF2GHIJKLMNOPQRSTUVWXYZ", "other") {
+		t.Error("actionKey() is the same for two clients")
+	}
+}
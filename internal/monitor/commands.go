@@ -0,0 +1,246 @@
+package monitor
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"restic-backup-checker/internal/state"
+	"restic-backup-checker/internal/telegram"
+	"restic-backup-checker/internal/templates"
+)
+
+// commandHelp lists the bot commands understood by the daemon
+const commandHelp = "Available commands:\n" +
+	"/status - current status of all clients\n" +
+	"/check - run a backup check now\n" +
+	"/client <name> - details and history of a client\n" +
+	"/silence <client> <duration> - mute alerts, e.g. 4h or 2d\n" +
+	"/unsilence [client] - unmute a client, or all clients"
+
+// handleCommand answers a bot command and returns the reply text
+func (m *Monitor) handleCommand(cmd telegram.Command) string {
+	m.log.Info("Received bot command /%s %s from %s", cmd.Name, strings.Join(cmd.Args, " "), cmd.From)
+
+	switch cmd.Name {
+	case "status":
+		return m.statusReply(cmd.ChatID)
+	case "check":
+		// Keep answering updates while the check runs
+		m.goSafe("Requested backup check", func() {
+			m.replyTo(cmd, m.checkReply(cmd.ChatID))
+		})
+		return "🔄 Running backup check..."
+	case "client":
+		if len(cmd.Args) != 1 {
+			return "Usage: /client &lt;name&gt;"
+		}
+		return m.clientReply(cmd.ChatID, cmd.Args[0])
+	case "silence":
+		if len(cmd.Args) != 2 {
+			return "Usage: /silence &lt;client&gt; &lt;duration&gt;"
+		}
+		return m.silenceReply(cmd.ChatID, cmd.Args[0], cmd.Args[1], cmd.From)
+	case "unsilence":
+		if len(cmd.Args) > 1 {
+			return "Usage: /unsilence [client]"
+		}
+		return m.unsilenceReply(cmd.ChatID, cmd.Args)
+	default:
+		return telegram.Escape(commandHelp)
+	}
+}
+
+// checkReply runs a backup check and renders its outcome for a chat
+func (m *Monitor) checkReply(chatID int64) string {
+	if err := m.CheckOnce(); err != nil {
+		return fmt.Sprintf("❌ Backup check failed: %s", telegram.Escape(err.Error()))
+	}
+	return "✅ Backup check completed.\n\n" + m.statusReply(chatID)
+}
+
+// replyTo sends a reply to a command that is answered later
+func (m *Monitor) replyTo(cmd telegram.Command, reply string) {
+	if err := m.telegram.SendMessageTo(cmd.ChatID, reply); err != nil {
+		m.log.Error("Failed to reply to /%s: %v", cmd.Name, err)
+	}
+}
+
+// statusReply renders the status table of the clients a chat may see
+func (m *Monitor) statusReply(chatID int64) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	clients := m.visibleClients(chatID, m.state.List())
+	if len(clients) == 0 {
+		return "No clients checked yet."
+	}
+
+	now := time.Now()
+	var b strings.Builder
+	b.WriteString("📋 <b>Backup Status</b>\n\n<pre>\n")
+	fmt.Fprintf(&b, "%-20s %-12s %s\n", "CLIENT", "STATUS", "LAST BACKUP")
+	for _, cs := range clients {
+		silenced := ""
+		if cs.IsSilenced(now) {
+			silenced = " 🔕"
+		}
+		fmt.Fprintf(&b, "%-20s %-12s %s%s\n", telegram.Escape(cs.Name()), cs.CurrentStatus(), formatAge(now, cs.LastBackup), silenced)
+	}
+	b.WriteString("</pre>")
+
+	return b.String()
+}
+
+// clientReply renders the details and recent history of a client
+func (m *Monitor) clientReply(chatID int64, name string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	matches := m.visibleClients(chatID, m.state.Find(name))
+	if len(matches) == 0 {
+		return fmt.Sprintf("Unknown client: <code>%s</code>", telegram.Escape(name))
+	}
+
+	now := time.Now()
+	var b strings.Builder
+	for _, cs := range matches {
+		status := "✅ " + cs.CurrentStatus()
+		if !cs.HasBackup {
+			status = "🚨 " + cs.CurrentStatus()
+		}
+
+		b.WriteString("<pre>\n")
+		fmt.Fprintf(&b, "Client:       %s\n", telegram.Escape(cs.Name()))
+		fmt.Fprintf(&b, "Monitor path: %s\n", telegram.Escape(m.config.FolderPath(cs.MonitorPath)))
+		fmt.Fprintf(&b, "Folder:       %s\n", telegram.Escape(clientFolder(cs)))
+		fmt.Fprintf(&b, "Status:       %s\n", status)
+		fmt.Fprintf(&b, "Last backup:  %s (%s)\n", formatTime(cs.LastBackup), formatAge(now, cs.LastBackup))
+		fmt.Fprintf(&b, "Recent files: %d\n", cs.FileCount)
+		fmt.Fprintf(&b, "Last check:   %s\n", formatTime(cs.LastChecked))
+		if cs.LastError != "" {
+			fmt.Fprintf(&b, "Error:        %s\n", telegram.Escape(cs.LastError))
+		}
+		if cs.ConsecutiveFailures > 0 {
+			fmt.Fprintf(&b, "Failing:      since %s, %d checks in a row\n", formatTime(cs.FailingSince), cs.ConsecutiveFailures)
+		}
+		if cs.EscalationLevel > 0 {
+			fmt.Fprintf(&b, "Escalation:   level %d\n", cs.EscalationLevel)
+		}
+		if cs.IsSilenced(now) {
+			fmt.Fprintf(&b, "Silenced:     until %s\n", formatTime(cs.SilencedUntil))
+		}
+		if cs.IsAcknowledged() {
+			fmt.Fprintf(&b, "Acknowledged: by %s at %s\n", telegram.Escape(cs.AckedBy), formatTime(cs.AckedAt))
+		}
+
+		if len(cs.History) > 0 {
+			b.WriteString("\nHistory:\n")
+			for i := len(cs.History) - 1; i >= 0 && i >= len(cs.History)-10; i-- {
+				entry := cs.History[i]
+				mark := "✅"
+				if !entry.HasBackup {
+					mark = "❌"
+				}
+				fmt.Fprintf(&b, "%s %s %d files %s\n", mark, formatTime(entry.Time), entry.FileCount, entry.Status)
+			}
+		}
+		b.WriteString("</pre>\n")
+	}
+
+	return b.String()
+}
+
+// silenceReply mutes notifications for a client for the given duration
+func (m *Monitor) silenceReply(chatID int64, name, durationStr, from string) string {
+	d, err := parseDuration(durationStr)
+	if err != nil || d <= 0 {
+		return fmt.Sprintf("Invalid duration: %s (use e.g. 30m, 4h or 2d)", telegram.Escape(durationStr))
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	matches := m.visibleClients(chatID, m.state.Find(name))
+	if len(matches) == 0 {
+		return fmt.Sprintf("Unknown client: <code>%s</code>", telegram.Escape(name))
+	}
+
+	until := time.Now().Add(d)
+	for _, cs := range matches {
+		cs.SilencedUntil = until
+	}
+	m.saveState()
+
+	m.log.Info("Client %s silenced until %s by %s", name, formatTime(until), from)
+	return fmt.Sprintf("🔕 <code>%s</code> silenced until %s", telegram.Escape(name), formatTime(until))
+}
+
+// unsilenceReply unmutes one client, or all clients the chat may see when no
+// name is given
+func (m *Monitor) unsilenceReply(chatID int64, args []string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var targets []*state.ClientState
+	if len(args) == 0 {
+		targets = m.visibleClients(chatID, m.state.List())
+	} else {
+		targets = m.visibleClients(chatID, m.state.Find(args[0]))
+		if len(targets) == 0 {
+			return fmt.Sprintf("Unknown client: <code>%s</code>", telegram.Escape(args[0]))
+		}
+	}
+
+	for _, cs := range targets {
+		cs.SilencedUntil = time.Time{}
+	}
+	m.saveState()
+
+	if len(args) == 0 {
+		return "🔔 All clients unsilenced"
+	}
+	return fmt.Sprintf("🔔 <code>%s</code> unsilenced", telegram.Escape(args[0]))
+}
+
+// clientFolder returns the path of a client's folder, or its ID if the path
+// is unknown
+func clientFolder(cs *state.ClientState) string {
+	if cs.FolderPath != "" {
+		return cs.FolderPath
+	}
+	return cs.FolderID
+}
+
+// saveState persists the state, logging failures. Callers must hold m.mu.
+func (m *Monitor) saveState() {
+	if err := m.state.Save(); err != nil {
+		m.log.Error("Failed to save monitoring state: %v", err)
+	}
+}
+
+// parseDuration parses a Go duration, additionally accepting a "d" suffix for days
+func parseDuration(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// formatTime formats a timestamp for display, or "Unknown" if unset
+func formatTime(t time.Time) string {
+	return templates.FormatTime(t)
+}
+
+// formatAge formats the time elapsed since t, e.g. "3h12m ago"
+func formatAge(now, t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	return templates.FormatAge(now.Sub(t)) + " ago"
+}
@@ -0,0 +1,54 @@
+package monitor
+
+import (
+	"time"
+
+	"restic-backup-checker/internal/config"
+	"restic-backup-checker/internal/state"
+)
+
+// defaultSeverity is the severity of a failing client before any escalation
+const defaultSeverity = "error"
+
+// escalationLevel returns how many escalation steps a client has reached and
+// the resulting severity. A healthy client is always at level 0.
+func escalationLevel(steps []config.EscalationStep, cs *state.ClientState, now time.Time) (int, string) {
+	if cs.ConsecutiveFailures == 0 {
+		return 0, ""
+	}
+
+	// Hours without a backup count from the newest snapshot, or from the
+	// first failed check if the client never had one
+	since := cs.LastBackup
+	if since.IsZero() {
+		since = cs.FailingSince
+	}
+	hours := now.Sub(since).Hours()
+
+	level, severity := 0, defaultSeverity
+	for i, step := range steps {
+		reached := (step.AfterFailures > 0 && cs.ConsecutiveFailures >= step.AfterFailures) ||
+			(step.AfterHours > 0 && hours >= step.AfterHours)
+		if !reached {
+			continue
+		}
+
+		level = i + 1
+		if step.Severity != "" {
+			severity = step.Severity
+		}
+	}
+
+	return level, severity
+}
+
+// escalationRoutes returns the names of the extra routes notified at a level
+func escalationRoutes(steps []config.EscalationStep, level int) []string {
+	var routes []string
+	for i := 0; i < level && i < len(steps); i++ {
+		if steps[i].Route != "" {
+			routes = append(routes, steps[i].Route)
+		}
+	}
+	return routes
+}
@@ -0,0 +1,93 @@
+package monitor
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"restic-backup-checker/internal/config"
+	"restic-backup-checker/internal/state"
+)
+
+func TestEscalationLevel(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	steps := []config.EscalationStep{
+		{AfterFailures: 3, Route: "admins"},
+		{AfterHours: 72, Severity: "critical"},
+		{AfterFailures: 10, Severity: "warning"},
+	}
+
+	tests := []struct {
+		name         string
+		cs           state.ClientState
+		wantLevel    int
+		wantSeverity string
+	}{
+		{
+			name: "healthy",
+			cs:   state.ClientState{LastBackup: now.Add(-100 * time.Hour)},
+		},
+		{
+			name:         "first failure",
+			cs:           state.ClientState{ConsecutiveFailures: 1, LastBackup: now.Add(-25 * time.Hour)},
+			wantSeverity: defaultSeverity,
+		},
+		{
+			name:         "failure count reached",
+			cs:           state.ClientState{ConsecutiveFailures: 3, LastBackup: now.Add(-30 * time.Hour)},
+			wantLevel:    1,
+			wantSeverity: defaultSeverity,
+		},
+		{
+			name:         "hours since last backup reached",
+			cs:           state.ClientState{ConsecutiveFailures: 1, LastBackup: now.Add(-72 * time.Hour)},
+			wantLevel:    2,
+			wantSeverity: "critical",
+		},
+		{
+			name:         "hours since first failure without backups",
+			cs:           state.ClientState{ConsecutiveFailures: 1, FailingSince: now.Add(-80 * time.Hour)},
+			wantLevel:    2,
+			wantSeverity: "critical",
+		},
+		{
+			name:         "last step reached sets its severity",
+			cs:           state.ClientState{ConsecutiveFailures: 10, LastBackup: now.Add(-80 * time.Hour)},
+			wantLevel:    3,
+			wantSeverity: "warning",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			level, severity := escalationLevel(steps, &tt.cs, now)
+			if level != tt.wantLevel || severity != tt.wantSeverity {
+				t.Errorf("escalationLevel() = %d, %q, want %d, %q", level, severity, tt.wantLevel, tt.wantSeverity)
+			}
+		})
+	}
+}
+
+func TestEscalationRoutes(t *testing.T) {
+	steps := []config.EscalationStep{
+		{AfterFailures: 3, Route: "admins"},
+		{AfterHours: 72},
+		{AfterFailures: 10, Route: "default"},
+	}
+
+	tests := []struct {
+		level int
+		want  []string
+	}{
+		{0, nil},
+		{1, []string{"admins"}},
+		{2, []string{"admins"}},
+		{3, []string{"admins", "default"}},
+		{5, []string{"admins", "default"}},
+	}
+	for _, tt := range tests {
+		if got := escalationRoutes(steps, tt.level); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("escalationRoutes(%d) = %v, want %v", tt.level, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"restic-backup-checker/internal/config"
+	"restic-backup-checker/internal/logger"
+	"restic-backup-checker/internal/onedrive"
+	"restic-backup-checker/internal/templates"
+)
+
+// refreshFolders looks up the current location of every monitored folder and
+// reports folders that were moved, renamed or deleted since the last check.
+// With notify, changes are sent to the default route and saved. Only checks
+// write the folder locations, so they are read without m.mu while checking.
+func (m *Monitor) refreshFolders(log *logger.Logger, client *onedrive.Client, notify bool) {
+	known := m.config.OneDrive.Folders
+
+	// Build a new map, bot commands read the current one under m.mu
+	folders := make(map[string]config.FolderInfo, len(m.config.OneDrive.MonitorPaths))
+	changed := len(known) != len(m.config.OneDrive.MonitorPaths)
+	for _, id := range m.config.OneDrive.MonitorPaths {
+		previous, seen := known[id]
+		current, event := m.lookupFolder(log, client, id, previous, seen)
+		folders[id] = current
+		changed = changed || current != previous
+
+		if event != "" && notify {
+			data := templates.FolderData{Event: event, ID: id, OldPath: previous.Path, Path: current.Path, Now: time.Now()}
+			if err := m.deliver(m.defaultRoute(), templates.TypeFolder, data, ""); err != nil {
+				log.Error("Failed to send monitored folder notice: %v", err)
+			}
+		}
+	}
+	m.mu.Lock()
+	m.config.OneDrive.Folders = folders
+	m.mu.Unlock()
+
+	if !changed || !notify {
+		return
+	}
+
+	// Lock to pick up tokens another process may have refreshed
+	if err := m.config.Lock(); err != nil {
+		log.Error("Failed to save monitored folder locations: %v", err)
+		return
+	}
+	defer m.config.Unlock()
+	if err := m.config.Save(); err != nil {
+		log.Error("Failed to save monitored folder locations: %v", err)
+	}
+}
+
+// lookupFolder returns the current location of a monitored folder and the
+// folder event to report, if any. Folders that cannot be looked up for other
+// reasons than being gone keep their last known location. Folders monitored
+// by path are looked up by path, so they are never moved, only gone.
+func (m *Monitor) lookupFolder(log *logger.Logger, client *onedrive.Client, ref string, previous config.FolderInfo, seen bool) (config.FolderInfo, string) {
+	folder, err := client.ResolveFolder(ref)
+	_, byPath := onedrive.ParsePath(ref)
+
+	var apiErr *onedrive.APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		if byPath {
+			// Don't keep checking a folder that is no longer at the path
+			previous.ID = ""
+		}
+		if previous.Missing {
+			return previous, ""
+		}
+		log.Warn("Monitored folder %s was deleted or is no longer accessible", m.config.FolderPath(ref))
+		previous.Missing = true
+		return previous, templates.FolderDeleted
+	}
+	if err != nil {
+		log.Warn("Failed to look up monitored folder %s: %v", m.config.FolderPath(ref), err)
+		return previous, ""
+	}
+
+	current := config.FolderInfo{Name: folder.Name, Path: folder.Path}
+	if byPath {
+		current.ID = folder.ID
+	}
+	switch {
+	case previous.Missing:
+		log.Info("Monitored folder %s is accessible again", current.Path)
+	case seen && previous.Path != "" && !samePath(previous.Path, current.Path):
+		log.Warn("Monitored folder %s was moved or renamed to %s", previous.Path, current.Path)
+		return current, templates.FolderMoved
+	}
+	return current, ""
+}
+
+// samePath reports whether a saved folder path is the current one. Paths
+// saved by older versions are percent-encoded.
+func samePath(saved, current string) bool {
+	if saved == current {
+		return true
+	}
+	decoded, err := url.PathUnescape(saved)
+	return err == nil && decoded == current
+}
+
+// listClientFolders lists the client folders of a monitored folder given by
+// ID or path. Paths are resolved by refreshFolders.
+func (m *Monitor) listClientFolders(client *onedrive.Client, ref string) ([]onedrive.Folder, error) {
+	if _, byPath := onedrive.ParsePath(ref); !byPath {
+		return client.GetSubfolders(ref)
+	}
+
+	id := m.config.OneDrive.Folders[ref].ID
+	if id == "" {
+		return nil, fmt.Errorf("no folder found at %s", ref)
+	}
+	return client.GetSubfolders(id)
+}
@@ -0,0 +1,95 @@
+package monitor
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"restic-backup-checker/internal/config"
+	"restic-backup-checker/internal/onedrive"
+	"restic-backup-checker/internal/templates"
+)
+
+// graphStub serves drive items by request path, e.g. /me/drive/items/ID or
+// /me/drive/root:/Backups:, and answers 404 for unknown items
+func graphStub(t *testing.T, items map[string]string) *onedrive.Client {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parent, ok := items[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if parent == "error" {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":              "ID-" + r.URL.Path,
+			"name":            "servers",
+			"folder":          map[string]interface{}{},
+			"parentReference": map[string]string{"path": parent},
+		})
+	}))
+	t.Cleanup(srv.Close)
+	return onedrive.NewClient("token").WithBaseURL(srv.URL)
+}
+
+func TestLookupFolder(t *testing.T) {
+	client := graphStub(t, map[string]string{
+		"/me/drive/items/F1":                  "/drive/root:/Backups",
+		"/me/drive/items/F2":                  "/drive/root:/Archive",
+		"/me/drive/items/F3":                  "/drive/root:/My%20Backups",
+		"/me/drive/items/BUSY":                "error",
+		"/me/drive/root:/Backups/servers:":    "/drive/root:/Backups",
+		"/me/drive/root:/My Backups/servers:": "/drive/root:/My%20Backups",
+	})
+	at := func(path string) config.FolderInfo { return config.FolderInfo{Name: "servers", Path: path} }
+
+	tests := []struct {
+		name      string
+		ref       string
+		previous  config.FolderInfo
+		seen      bool
+		want      config.FolderInfo
+		wantEvent string
+	}{
+		{"first lookup", "F1", config.FolderInfo{}, false, at("/Backups/servers"), ""},
+		{"unchanged", "F1", at("/Backups/servers"), true, at("/Backups/servers"), ""},
+		{"moved", "F2", at("/Backups/servers"), true, at("/Archive/servers"), templates.FolderMoved},
+		{"saved percent-encoded", "F3", at("/My%20Backups/servers"), true, at("/My Backups/servers"), ""},
+		{"deleted", "GONE", at("/Backups/servers"), true, config.FolderInfo{Name: "servers", Path: "/Backups/servers", Missing: true}, templates.FolderDeleted},
+		{"still deleted", "GONE", config.FolderInfo{Name: "servers", Path: "/Backups/servers", Missing: true}, true, config.FolderInfo{Name: "servers", Path: "/Backups/servers", Missing: true}, ""},
+		{"accessible again", "F1", config.FolderInfo{Name: "servers", Path: "/Backups/servers", Missing: true}, true, at("/Backups/servers"), ""},
+		{"lookup failed", "BUSY", at("/Backups/servers"), true, at("/Backups/servers"), ""},
+		{
+			name: "by path",
+			ref:  "/Backups/servers",
+			want: config.FolderInfo{ID: "ID-/me/drive/root:/Backups/servers:", Name: "servers", Path: "/Backups/servers"},
+		},
+		{
+			name: "by path with a space",
+			ref:  "/My Backups/servers",
+			want: config.FolderInfo{ID: "ID-/me/drive/root:/My Backups/servers:", Name: "servers", Path: "/My Backups/servers"},
+		},
+		{
+			name:      "gone from path",
+			ref:       "/Old/servers",
+			previous:  config.FolderInfo{ID: "F9", Name: "servers", Path: "/Old/servers"},
+			seen:      true,
+			want:      config.FolderInfo{Name: "servers", Path: "/Old/servers", Missing: true},
+			wantEvent: templates.FolderDeleted,
+		},
+	}
+
+	m := testMonitor(t, &config.Config{})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, event := m.lookupFolder(m.log, client, tt.ref, tt.previous, tt.seen)
+			if got != tt.want || event != tt.wantEvent {
+				t.Errorf("lookupFolder() = %+v, %q, want %+v, %q", got, event, tt.want, tt.wantEvent)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package monitor
+
+import (
+	"strconv"
+	"time"
+
+	"restic-backup-checker/internal/state"
+	"restic-backup-checker/internal/templates"
+)
+
+// incidentChannel is a paging integration with a trigger/resolve lifecycle
+type incidentChannel interface {
+	Name() string
+	Trigger(dedupKey, summary, severity string, details map[string]string) error
+	Resolve(dedupKey string) error
+}
+
+// incidentKey returns the stable dedup key of a client's incident
+func incidentKey(status BackupStatus) string {
+	return "restic-backup-checker/" + state.ClientKey(status.MonitorPath, status.stateName())
+}
+
+// incidentUpdate is an incident to trigger or resolve, picked under the
+// monitor mutex and sent after releasing it
+type incidentUpdate struct {
+	status  BackupStatus
+	resolve bool
+}
+
+// updateIncidents triggers incidents for newly failing clients, re-triggers
+// them with the new severity when a client escalates, and resolves them once
+// the client's backup is fresh again
+func (m *Monitor) updateIncidents(statuses []BackupStatus) {
+	if len(m.incidents) == 0 {
+		return
+	}
+
+	now := time.Now()
+	var updates []incidentUpdate
+	m.mu.Lock()
+	for _, status := range statuses {
+		cs := m.state.Client(status.MonitorPath, status.stateName())
+		switch {
+		case !status.HasBackup && (!cs.IncidentOpen || status.EscalationLevel > cs.IncidentLevel) && !cs.IsSilenced(now):
+			updates = append(updates, incidentUpdate{status: status})
+		case status.HasBackup && cs.IncidentOpen:
+			updates = append(updates, incidentUpdate{status: status, resolve: true})
+		}
+	}
+	m.mu.Unlock()
+
+	var sent []incidentUpdate
+	for _, u := range updates {
+		status := u.status
+		key := incidentKey(status)
+		ok := m.sendIncident(key, func(ch incidentChannel) error {
+			if u.resolve {
+				return ch.Resolve(key)
+			}
+			data := templates.AlertData{Client: clientData(status, now), Now: now}
+			summary, err := m.templates.Render(templates.TypeIncident, ch.Name(), data)
+			if err != nil {
+				return err
+			}
+			return ch.Trigger(key, summary, status.Severity, incidentDetails(status))
+		})
+		if ok {
+			sent = append(sent, u)
+		}
+	}
+	if len(sent) == 0 {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, u := range sent {
+		cs := m.state.Client(u.status.MonitorPath, u.status.stateName())
+		if u.resolve {
+			cs.IncidentOpen = false
+			cs.IncidentLevel = 0
+		} else {
+			cs.IncidentOpen = true
+			cs.IncidentLevel = u.status.EscalationLevel
+		}
+	}
+	m.saveState()
+}
+
+// sendIncident runs send against every paging channel and reports whether
+// all of them succeeded, so failed deliveries are retried on the next check
+func (m *Monitor) sendIncident(key string, send func(incidentChannel) error) bool {
+	ok := true
+	for _, ch := range m.incidents {
+		if err := send(ch); err != nil {
+			m.log.Error("Failed to update %s incident %s: %v", ch.Name(), key, err)
+			if m.metrics != nil {
+				m.metrics.SendFailed(ch.Name())
+			}
+			ok = false
+		}
+	}
+	return ok
+}
+
+// incidentDetails returns the custom fields attached to an incident
+func incidentDetails(status BackupStatus) map[string]string {
+	details := map[string]string{
+		"client":       status.ClientName,
+		"monitor_path": status.MonitorName,
+		"folder_id":    status.FolderID,
+		"folder_path":  status.FolderPath,
+		"file_count":   strconv.Itoa(status.FileCount),
+		"escalation":   strconv.Itoa(status.EscalationLevel),
+		"last_backup":  "Unknown",
+	}
+	if !status.LastBackup.IsZero() {
+		details["last_backup"] = templates.FormatTime(status.LastBackup)
+	}
+	if status.Error != nil {
+		details["error"] = status.Error.Error()
+	}
+	return details
+}
@@ -0,0 +1,144 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"restic-backup-checker/internal/config"
+)
+
+// fakeIncidents records the incidents sent to it and fails while failing is set
+type fakeIncidents struct {
+	m       *Monitor
+	calls   []string
+	failing bool
+}
+
+func (f *fakeIncidents) Name() string { return "pagerduty" }
+
+func (f *fakeIncidents) Trigger(dedupKey, summary, severity string, details map[string]string) error {
+	return f.record(fmt.Sprintf("trigger %s %s", dedupKey, severity))
+}
+
+func (f *fakeIncidents) Resolve(dedupKey string) error {
+	return f.record("resolve " + dedupKey)
+}
+
+func (f *fakeIncidents) record(call string) error {
+	if !f.m.mu.TryLock() {
+		return errors.New("sent while holding the monitor mutex")
+	}
+	f.m.mu.Unlock()
+	if f.failing {
+		return errors.New("unreachable")
+	}
+	f.calls = append(f.calls, call)
+	return nil
+}
+
+// failingStatus returns the status of a client without a recent backup
+func failingStatus(name string, level int, severity string) BackupStatus {
+	return BackupStatus{ClientName: name, MonitorPath: "F1", FolderID: "id-" + name, EscalationLevel: level, Severity: severity}
+}
+
+func TestUpdateIncidents(t *testing.T) {
+	m := testMonitor(t, &config.Config{})
+	fake := &fakeIncidents{m: m}
+	m.incidents = []incidentChannel{fake}
+	healthy := BackupStatus{ClientName: "web01", MonitorPath: "F1", FolderID: "id-web01", HasBackup: true}
+
+	steps := []struct {
+		name     string
+		statuses []BackupStatus
+		failing  bool
+		want     []string
+	}{
+		{"healthy client", []BackupStatus{healthy}, false, nil},
+		{"newly failing", []BackupStatus{failingStatus("web01", 0, "error")}, false, []string{"trigger restic-backup-checker/F1/web01 error"}},
+		{"still failing", []BackupStatus{failingStatus("web01", 0, "error")}, false, nil},
+		{"escalated", []BackupStatus{failingStatus("web01", 1, "critical")}, false, []string{"trigger restic-backup-checker/F1/web01 critical"}},
+		{"same level", []BackupStatus{failingStatus("web01", 1, "critical")}, false, nil},
+		{"resolve fails", []BackupStatus{healthy}, true, nil},
+		{"resolve retried", []BackupStatus{healthy}, false, []string{"resolve restic-backup-checker/F1/web01"}},
+		{"resolved", []BackupStatus{healthy}, false, nil},
+	}
+
+	for _, step := range steps {
+		fake.calls = nil
+		fake.failing = step.failing
+		m.updateIncidents(step.statuses)
+		if !reflect.DeepEqual(fake.calls, step.want) {
+			t.Errorf("%s: sent %v, want %v", step.name, fake.calls, step.want)
+		}
+	}
+}
+
+func TestUpdateIncidentsSilenced(t *testing.T) {
+	m := testMonitor(t, &config.Config{})
+	fake := &fakeIncidents{m: m}
+	m.incidents = []incidentChannel{fake}
+	m.state.Client("F1", "web01").SilencedUntil = time.Now().Add(time.Hour)
+
+	m.updateIncidents([]BackupStatus{failingStatus("web01", 0, "error")})
+	if len(fake.calls) != 0 {
+		t.Errorf("sent %v for a silenced client", fake.calls)
+	}
+}
+
+func TestForgetRemovedClients(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.OneDrive.MonitorPaths = []string{"F1", "F2"}
+	m := testMonitor(t, cfg)
+	fake := &fakeIncidents{m: m}
+	m.incidents = []incidentChannel{fake}
+
+	m.state.Client("F1", "web01")
+	m.state.Client("F1", "gone").IncidentOpen = true
+	m.state.Client("F2", "unlisted")
+	m.state.Client("F3", "unmonitored")
+
+	listed := map[string]bool{"F1": true}
+	found := map[string]bool{"F1/web01": true}
+
+	fake.failing = true
+	m.forgetRemovedClients(listed, found)
+	if got := len(m.state.List()); got != 3 {
+		t.Errorf("%d clients kept after failing to resolve an incident, want 3", got)
+	}
+
+	fake.failing = false
+	m.forgetRemovedClients(listed, found)
+	var kept []string
+	for _, cs := range m.state.List() {
+		kept = append(kept, cs.MonitorPath+"/"+cs.ClientName)
+	}
+	sort.Strings(kept)
+	if want := []string{"F1/web01", "F2/unlisted"}; !reflect.DeepEqual(kept, want) {
+		t.Errorf("kept %v, want %v", kept, want)
+	}
+	if want := []string{"resolve restic-backup-checker/F1/gone"}; !reflect.DeepEqual(fake.calls, want) {
+		t.Errorf("sent %v, want %v", fake.calls, want)
+	}
+}
+
+func TestIncidentKey(t *testing.T) {
+	web := BackupStatus{ClientName: "web01", MonitorPath: "F1", FolderID: "C1"}
+	otherPath := BackupStatus{ClientName: "web01", MonitorPath: "F2", FolderID: "C2"}
+	// A monitored path that could not be listed keeps its key when renamed
+	listFailed := pathStatus("F1", "/Backups/servers", errors.New("unavailable"))
+	renamed := pathStatus("F1", "/Backups/old-servers", errors.New("unavailable"))
+
+	if got, want := incidentKey(web), "restic-backup-checker/F1/web01"; got != want {
+		t.Errorf("incidentKey() = %q, want %q", got, want)
+	}
+	if incidentKey(web) == incidentKey(otherPath) {
+		t.Error("incidentKey() is the same for clients in two monitored paths")
+	}
+	if incidentKey(listFailed) != incidentKey(renamed) {
+		t.Errorf("incidentKey() changed with the folder path: %q, %q", incidentKey(listFailed), incidentKey(renamed))
+	}
+}
@@ -1,14 +1,29 @@
 package monitor
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"path"
+	"runtime/debug"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"restic-backup-checker/internal/config"
+	"restic-backup-checker/internal/email"
+	"restic-backup-checker/internal/heartbeat"
 	"restic-backup-checker/internal/logger"
+	"restic-backup-checker/internal/metrics"
 	"restic-backup-checker/internal/onedrive"
+	"restic-backup-checker/internal/opsgenie"
+	"restic-backup-checker/internal/pagerduty"
+	"restic-backup-checker/internal/state"
 	"restic-backup-checker/internal/telegram"
+	"restic-backup-checker/internal/templates"
+	"restic-backup-checker/internal/webhook"
 
 	"golang.org/x/oauth2"
 )
@@ -18,146 +33,422 @@ type Monitor struct {
 	config       *config.Config
 	onedriveAuth *onedrive.Authenticator
 	telegram     *telegram.Client
+	email        *email.Client
+	webhook      *webhook.Client
+	heartbeat    *heartbeat.Client
+	metrics      *metrics.Metrics
+	incidents    []incidentChannel
+	templates    *templates.Renderer
+	state        *state.Store
 	stopChan     chan struct{}
+	stopOnce     sync.Once
+	stopErr      error // why the service stopped, nil if stopped by Stop
 	wg           sync.WaitGroup
+	mu           sync.Mutex // guards state, and config.OneDrive.Folders outside checks
+	checkMu      sync.Mutex // serialises CheckOnce
+	digestMu     sync.Mutex // serialises flushDigests
+	log          *logger.Logger
+}
+
+// CheckOptions controls a single check run
+type CheckOptions struct {
+	Notify  bool     // record state and send notifications, incidents and heartbeat pings
+	Clients []string // glob patterns of client names to check, all if empty
+}
+
+// selects returns true if the client is to be checked
+func (o CheckOptions) selects(clientName string) bool {
+	if len(o.Clients) == 0 {
+		return true
+	}
+	for _, pattern := range o.Clients {
+		if ok, _ := path.Match(pattern, clientName); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckResult holds the outcome of a check run
+type CheckResult struct {
+	Run     templates.Run      `json:"run"`
+	Clients []templates.Client `json:"clients"`
 }
 
 // BackupStatus represents the status of a backup check
 type BackupStatus struct {
-	ClientName string
-	FolderPath string
-	HasBackup  bool
-	FileCount  int
-	LastBackup time.Time
-	Error      error
+	ClientName  string
+	MonitorPath string // ID of the monitored folder
+	MonitorName string // path of the monitored folder, or its ID if unknown
+	FolderID    string
+	FolderPath  string // full path of the client folder, or its ID if unknown
+	HasBackup   bool
+	FileCount   int // snapshots created in the last 24 hours
+	Snapshots   int // all snapshots
+	LastBackup  time.Time
+	Status      string // one of the templates.Status* values
+	Error       error
+
+	// Escalation state, filled in from the persisted client state
+	ConsecutiveFailures int
+	FailingSince        time.Time
+	EscalationLevel     int
+	Severity            string
+}
+
+// stateName returns the name the client's state and incident are kept under:
+// the client folder name, or the ID of a monitored path that could not be
+// listed, which stays the same when the folder is renamed
+func (s BackupStatus) stateName() string {
+	if s.FolderID == s.MonitorPath {
+		return s.MonitorPath
+	}
+	return s.ClientName
 }
 
 // New creates a new Monitor instance
 func New(cfg *config.Config) *Monitor {
+	// Tell the profiles apart when the daemon runs several
+	log := logger.With()
+	if cfg.Profile() != config.DefaultProfile {
+		log = log.With("profile", cfg.Profile())
+	}
+
 	auth := onedrive.NewAuthenticator()
 	tg := telegram.New(cfg.Telegram.BotToken, cfg.Telegram.ChatID)
 
+	var mailer *email.Client
+	if cfg.Email.SMTPHost != "" {
+		mailer = email.New(cfg.Email.SMTPHost, cfg.Email.SMTPPort, cfg.Email.Username, cfg.Email.Password, cfg.Email.From)
+	}
+
+	var hb *heartbeat.Client
+	if cfg.Heartbeat.URL != "" {
+		hb = heartbeat.New(cfg.Heartbeat.URL, cfg.Heartbeat.StartURL, cfg.Heartbeat.FailURL)
+	}
+
+	var mt *metrics.Metrics
+	if cfg.Metrics.Listen != "" {
+		mt = metrics.New()
+	}
+
+	var incidents []incidentChannel
+	if cfg.PagerDuty.RoutingKey != "" {
+		incidents = append(incidents, pagerduty.New(cfg.PagerDuty.RoutingKey, cfg.PagerDuty.BaseURL))
+	}
+	if cfg.Opsgenie.APIKey != "" {
+		incidents = append(incidents, opsgenie.New(cfg.Opsgenie.APIKey, cfg.Opsgenie.BaseURL))
+	}
+
+	renderer, err := templates.New(cfg.Templates)
+	if err != nil {
+		log.Error("Failed to load notification templates, using defaults: %v", err)
+		renderer = templates.Default()
+	}
+
+	store, err := state.Load(cfg.StatePath())
+	if err != nil {
+		log.Error("Failed to load monitoring state, starting fresh: %v", err)
+	}
+
 	return &Monitor{
 		config:       cfg,
 		onedriveAuth: auth,
 		telegram:     tg,
+		email:        mailer,
+		webhook:      webhook.New(),
+		heartbeat:    hb,
+		metrics:      mt,
+		incidents:    incidents,
+		templates:    renderer,
+		state:        store,
 		stopChan:     make(chan struct{}),
+		log:          log,
 	}
 }
 
 // Start starts the monitoring service
 func (m *Monitor) Start() error {
 	if !m.config.Monitoring.Enabled {
-		logger.Info("Monitoring is disabled")
+		m.log.Info("Monitoring is disabled")
 		return nil
 	}
 
-	logger.Info("Starting backup monitoring service...")
+	m.log.Info("Starting backup monitoring service...")
 
 	// Run initial check
 	if err := m.CheckOnce(); err != nil {
-		logger.Error("Initial backup check failed: %v", err)
+		m.log.Error("Initial backup check failed: %v", err)
+	}
+
+	// Serve the dashboard and JSON API
+	if m.config.Web.Listen != "" {
+		m.goSafe("Dashboard", m.serveWeb)
+	}
+
+	// Expose Prometheus metrics, unless served by the dashboard
+	if m.metrics != nil && m.config.Metrics.Listen != m.config.Web.Listen {
+		m.goSafe("Metrics endpoint", func() {
+			m.log.Info("Serving metrics on %s/metrics", m.config.Metrics.Listen)
+			if err := m.metrics.Serve(m.config.Metrics.Listen, m.stopChan); err != nil {
+				m.log.Error("Metrics endpoint stopped: %v", err)
+			}
+		})
 	}
 
 	// Start periodic monitoring
-	m.wg.Add(1)
-	go m.monitoringLoop()
+	m.goSafe("Periodic monitoring", m.monitoringLoop)
 
-	logger.Info("Backup monitoring service started")
+	// Answer bot commands
+	if m.telegram != nil {
+		m.goSafe("Bot", func() {
+			m.telegram.Listen(m.stopChan, m.config.Telegram.AllowedChatIDs, m.routeChatIDs(), m.handleCommand, m.handleCallback)
+		})
+	}
+
+	m.log.Info("Backup monitoring service started")
 
 	// Wait for stop signal
 	<-m.stopChan
 	m.wg.Wait()
 
-	return nil
+	return m.stopErr
 }
 
 // Stop stops the monitoring service
 func (m *Monitor) Stop() {
-	logger.Info("Stopping backup monitoring service...")
-	close(m.stopChan)
+	m.log.Info("Stopping backup monitoring service...")
+	m.stop(nil)
 	m.wg.Wait()
-	logger.Info("Backup monitoring service stopped")
+	m.log.Info("Backup monitoring service stopped")
+}
+
+// stop closes stopChan once, recording why the service stopped
+func (m *Monitor) stop(err error) {
+	m.stopOnce.Do(func() {
+		m.stopErr = err
+		close(m.stopChan)
+	})
+}
+
+// goSafe runs fn in a goroutine that Stop waits for. A panic in fn is
+// logged and stops the service, and Start returns it as an error, so a
+// profile that fails doesn't take down the others running in the process.
+func (m *Monitor) goSafe(name string, fn func()) {
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		defer func() {
+			if r := recover(); r != nil {
+				m.log.Error("%s panicked, stopping monitoring: %v\n%s", name, r, debug.Stack())
+				m.stop(fmt.Errorf("%s panicked: %v", strings.ToLower(name), r))
+			}
+		}()
+		fn()
+	}()
 }
 
 // CheckOnce performs a single backup check
 func (m *Monitor) CheckOnce() error {
-	logger.Info("Starting backup check...")
+	_, err := m.Check(CheckOptions{Notify: true})
+	return err
+}
+
+// Check performs a single backup check of the clients selected by opts. The
+// result is returned even if the check failed, covering the clients checked.
+func (m *Monitor) Check(opts CheckOptions) (*CheckResult, error) {
+	m.checkMu.Lock()
+	defer m.checkMu.Unlock()
+
+	runID := newRunID()
+	log := m.log.With("run_id", runID)
+	log.Info("Starting backup check...")
+	started := time.Now()
+
+	if opts.Notify && m.heartbeat != nil {
+		if err := m.heartbeat.Start(); err != nil {
+			log.Error("Failed to send heartbeat start ping: %v", err)
+		}
+	}
+
+	statuses, run, notifyErr, err := m.runCheck(log, started, opts)
+
+	if opts.Notify {
+		m.recordRun(runID, started, run, err)
+		if m.heartbeat != nil {
+			m.sendHeartbeat(time.Since(started), run, notifyErr, err)
+		}
+	}
+
+	now := time.Now()
+	result := &CheckResult{Run: run, Clients: []templates.Client{}}
+	for _, status := range statuses {
+		result.Clients = append(result.Clients, clientData(status, now))
+	}
+	return result, err
+}
+
+// newRunID returns a random ID identifying a check run in logs and the API
+func newRunID() string {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(b)
+}
+
+// runCheck checks the selected clients and, if requested, records their
+// state and sends notifications. Failures to notify are returned separately
+// from errors that prevented the check itself.
+func (m *Monitor) runCheck(log *logger.Logger, started time.Time, opts CheckOptions) (statuses []BackupStatus, run templates.Run, notifyErr error, err error) {
+	run = templates.Run{Started: started}
 
 	// Refresh token if needed
 	if err := m.refreshTokenIfNeeded(); err != nil {
-		return fmt.Errorf("failed to refresh token: %w", err)
+		if opts.Notify {
+			m.reportAuthFailure(err)
+		}
+		return nil, run, nil, fmt.Errorf("failed to refresh token: %w", err)
+	}
+	if opts.Notify {
+		m.reportAuthSuccess()
+		m.remindTokenExpiry()
 	}
 
 	client := onedrive.NewClient(m.config.OneDrive.AccessToken)
+	if m.metrics != nil {
+		m.metrics.SetTokenExpiry(time.Unix(m.config.OneDrive.TokenExpiry, 0), m.refreshTokenExpiry())
+		client.WithObserver(m.metrics.GraphRequest)
+	}
+
+	// Look up where the monitored folders are now
+	m.refreshFolders(log, client, opts.Notify)
 
-	var statuses []BackupStatus
-	var successCount, failedCount int
-	var failedClients []string
+	// Clients found in monitored paths whose client folders could be listed
+	listed := make(map[string]bool)
+	found := make(map[string]bool)
 
 	// Check each monitored path
 	for i, folderID := range m.config.OneDrive.MonitorPaths {
-		logger.Debug("Checking monitored path %d/%d: %s", i+1, len(m.config.OneDrive.MonitorPaths), folderID)
+		folderPath := m.config.FolderPath(folderID)
+		pathLog := log.With("monitor_path", folderID, "folder", folderPath)
+		pathLog.Debug("Checking monitored path %d/%d: %s", i+1, len(m.config.OneDrive.MonitorPaths), folderPath)
 
 		// Get folder info for client names
-		subfolders, err := client.GetSubfolders(folderID)
+		subfolders, err := m.listClientFolders(client, folderID)
 		if err != nil {
-			logger.Error("Failed to get subfolders for %s: %v", folderID, err)
+			pathLog.Error("Failed to get subfolders for %s: %v", folderPath, err)
+			status := pathStatus(folderID, folderPath, err)
+			statuses = append(statuses, status)
+			run.Count(status.Status)
 			continue
 		}
+		if opts.Notify {
+			m.clearPathStatus(folderID)
+		}
+		listed[folderID] = true
+		for _, subfolder := range subfolders {
+			found[state.ClientKey(folderID, subfolder.Name)] = true
+		}
 
-		logger.Debug("Found %d client folders in monitored path: %s", len(subfolders), folderID)
+		pathLog.Debug("Found %d client folders in monitored path: %s", len(subfolders), folderPath)
 
 		// Check each client folder
 		for _, subfolder := range subfolders {
-			logger.Debug("Checking client: %s (ID: %s)", subfolder.Name, subfolder.ID)
+			if !opts.selects(subfolder.Name) {
+				continue
+			}
+
+			clientLog := pathLog.With("client", subfolder.Name, "folder_id", subfolder.ID)
+			clientLog.Debug("Checking client: %s (ID: %s)", subfolder.Name, subfolder.ID)
 
-			status := m.checkClientBackup(client, subfolder.ID, subfolder.Name)
+			status := m.checkClientBackup(clientLog, client, folderID, subfolder.ID, subfolder.Name)
 			statuses = append(statuses, status)
+			run.Count(status.Status)
 
-			if status.Error != nil {
-				logger.Error("Error checking client %s: %v", status.ClientName, status.Error)
-				failedCount++
-				failedClients = append(failedClients, status.ClientName)
-			} else if status.HasBackup {
-				successCount++
-				logger.Info("✅ Client %s: Backup found in last 24 hours (%d files)",
+			switch status.Status {
+			case templates.StatusOK:
+				clientLog.Info("✅ Client %s: Backup found in last 24 hours (%d files)",
 					status.ClientName, status.FileCount)
-			} else {
-				failedCount++
-				failedClients = append(failedClients, status.ClientName)
-				lastBackupStr := "Unknown"
-				if !status.LastBackup.IsZero() {
-					lastBackupStr = status.LastBackup.Format("2006-01-02 15:04:05")
-				}
-				logger.Error("❌ Client %s: No backup in last 24 hours, last backup: %s",
-					status.ClientName, lastBackupStr)
+			case templates.StatusStale, templates.StatusNoSnapshots:
+				clientLog.Error("❌ Client %s: %s, last backup: %s", status.ClientName,
+					templates.Describe(status.Status), templates.FormatTime(status.LastBackup))
+			default:
+				clientLog.Error("⚠️ Client %s: %s: %v", status.ClientName,
+					templates.Describe(status.Status), status.Error)
 			}
 		}
 	}
 
-	// Send notifications
-	if err := m.sendNotifications(statuses, successCount, failedCount, failedClients); err != nil {
-		logger.Error("Failed to send notifications: %v", err)
+	run.Duration = time.Since(started)
+
+	if opts.Notify {
+		// Remember results for bot commands and incident tracking
+		m.recordStatuses(statuses)
+
+		// Send notifications
+		if m.metrics != nil {
+			m.metrics.ObserveRun(run.Duration, metricsClients(statuses))
+		}
+		if notifyErr = m.sendNotifications(statuses, run); notifyErr != nil {
+			log.Error("Failed to send notifications: %v", notifyErr)
+		}
+
+		// Open or resolve incidents on paging channels
+		m.updateIncidents(statuses)
+		m.forgetRemovedClients(listed, found)
+
+		// Deliver alerts held back during quiet hours that have ended
+		m.flushDigests()
 	}
 
-	logger.Info("Backup check completed. Success: %d, Failed: %d", successCount, failedCount)
-	return nil
+	log.With("duration", run.Duration).Info("Backup check completed. Success: %d, Failed: %d (stale: %d, no snapshots: %d, not a repo: %d, check errors: %d, auth errors: %d)",
+		run.Successful, run.Failed, run.Stale, run.NoSnapshots, run.NotARepo, run.CheckErrors, run.AuthErrors)
+	return statuses, run, notifyErr, nil
+}
+
+// sendHeartbeat reports the outcome of a check run to the dead man's switch.
+// The run fails if the check could not be performed or notifications could
+// not be delivered; missing backups are not a failure of the checker.
+func (m *Monitor) sendHeartbeat(duration time.Duration, run templates.Run, notifyErr, err error) {
+	report := fmt.Sprintf("duration_seconds=%.3f\nclients_total=%d\nclients_ok=%d\nclients_failed=%d\nclients_check_errors=%d\nclients_auth_errors=%d\n",
+		duration.Seconds(), run.Total, run.Successful, run.Failed, run.CheckErrors, run.AuthErrors)
+
+	var pingErr error
+	switch {
+	case err != nil:
+		pingErr = m.heartbeat.Fail(duration, report+"error="+err.Error()+"\n")
+	case notifyErr != nil:
+		pingErr = m.heartbeat.Fail(duration, report+"notification_error="+notifyErr.Error()+"\n")
+	default:
+		pingErr = m.heartbeat.Success(duration, report)
+	}
+
+	if pingErr != nil {
+		m.log.Error("Failed to send heartbeat ping: %v", pingErr)
+		if m.metrics != nil {
+			m.metrics.SendFailed("heartbeat")
+		}
+	}
 }
 
 // monitoringLoop runs the periodic monitoring
 func (m *Monitor) monitoringLoop() {
-	defer m.wg.Done()
-
 	ticker := time.NewTicker(time.Duration(m.config.Monitoring.CheckInterval) * time.Minute)
 	defer ticker.Stop()
 
+	// Quiet hours end independently of the check interval
+	digestTicker := time.NewTicker(time.Minute)
+	defer digestTicker.Stop()
+
 	for {
 		select {
 		case <-ticker.C:
 			if err := m.CheckOnce(); err != nil {
-				logger.Error("Periodic backup check failed: %v", err)
+				m.log.Error("Periodic backup check failed: %v", err)
 			}
+		case <-digestTicker.C:
+			m.flushDigests()
 		case <-m.stopChan:
 			return
 		}
@@ -165,119 +456,345 @@ func (m *Monitor) monitoringLoop() {
 }
 
 // checkClientBackup checks backup status for a single client
-func (m *Monitor) checkClientBackup(client *onedrive.Client, folderID, clientName string) BackupStatus {
+func (m *Monitor) checkClientBackup(log *logger.Logger, client *onedrive.Client, monitorPath, folderID, clientName string) BackupStatus {
 	status := BackupStatus{
-		ClientName: clientName,
-		FolderPath: folderID,
+		ClientName:  clientName,
+		MonitorPath: monitorPath,
+		MonitorName: m.config.FolderPath(monitorPath),
+		FolderID:    folderID,
+		FolderPath:  folderID,
+	}
+	if status.MonitorName != monitorPath {
+		status.FolderPath = path.Join(status.MonitorName, clientName)
 	}
 
-	// Check if there are backups in the last 24 hours
-	hasBackup, recentFiles, err := client.CheckTodayBackups(folderID)
+	allFiles, err := client.GetAllSnapshots(folderID)
 	if err != nil {
+		status.Status = classifyError(err)
 		status.Error = err
-		logger.Error("Failed to check backup for client %s: %v", clientName, err)
+		log.Error("Failed to check backup for client %s: %v", clientName, err)
 		return status
 	}
 
-	status.HasBackup = hasBackup
-	status.FileCount = len(recentFiles)
-
-	// Get all backup files to find the most recent one
-	allFiles, err := client.GetAllSnapshots(folderID)
-	if err != nil {
-		logger.Error("Failed to get all snapshots for client %s: %v", clientName, err)
-		// Continue with partial data - we still have today's backup status
-	} else {
-		// Find the most recent backup from all files
-		var latestBackup time.Time
-		for _, file := range allFiles {
-			if file.CreatedTime.After(latestBackup) {
-				latestBackup = file.CreatedTime
-			}
+	// Count snapshots from the last 24 hours and find the most recent one
+	status.Snapshots = len(allFiles)
+	since := time.Now().Add(-24 * time.Hour)
+	for _, file := range allFiles {
+		if file.CreatedTime.After(since) {
+			status.FileCount++
 		}
-		status.LastBackup = latestBackup
-
-		// Log backup information for debugging
-		if !latestBackup.IsZero() {
-			logger.Debug("Client %s: Last backup was %s, Recent backup (24h): %v",
-				clientName, latestBackup.Format("2006-01-02 15:04:05"), hasBackup)
-		} else {
-			logger.Debug("Client %s: No backups found, Recent backup (24h): %v",
-				clientName, hasBackup)
+		if file.CreatedTime.After(status.LastBackup) {
+			status.LastBackup = file.CreatedTime
 		}
 	}
+	status.HasBackup = status.FileCount > 0
+
+	switch {
+	case status.HasBackup:
+		status.Status = templates.StatusOK
+	case len(allFiles) == 0:
+		status.Status = templates.StatusNoSnapshots
+	default:
+		status.Status = templates.StatusStale
+	}
+
+	// Log backup information for debugging
+	if !status.LastBackup.IsZero() {
+		log.Debug("Client %s: Last backup was %s, Recent backup (24h): %v",
+			clientName, status.LastBackup.Format("2006-01-02 15:04:05"), status.HasBackup)
+	} else {
+		log.Debug("Client %s: No backups found, Recent backup (24h): %v",
+			clientName, status.HasBackup)
+	}
 
 	return status
 }
 
-// sendNotifications sends appropriate notifications based on backup status
-func (m *Monitor) sendNotifications(statuses []BackupStatus, successCount, failedCount int, failedClients []string) error {
-	if m.telegram == nil {
-		return fmt.Errorf("telegram client not initialized")
+// pathStatus reports a monitored path whose client folders could not be
+// listed as a client of its own, so the failure is alerted on like any other.
+// It is shown with the folder's path and kept under its ID.
+func pathStatus(monitorPath, folderPath string, err error) BackupStatus {
+	return BackupStatus{
+		ClientName:  folderPath,
+		MonitorPath: monitorPath,
+		MonitorName: folderPath,
+		FolderID:    monitorPath,
+		FolderPath:  folderPath,
+		Status:      classifyError(err),
+		Error:       fmt.Errorf("failed to list client folders: %w", err),
 	}
+}
 
-	// Send individual alerts for failed backups
+// metricsClients converts check results for the metrics endpoint
+func metricsClients(statuses []BackupStatus) []metrics.Client {
+	clients := make([]metrics.Client, 0, len(statuses))
 	for _, status := range statuses {
-		if !status.HasBackup {
-			lastBackupStr := "Unknown"
-			if !status.LastBackup.IsZero() {
-				lastBackupStr = status.LastBackup.Format("2006-01-02 15:04:05")
-			}
+		clients = append(clients, metrics.Client{
+			Name:            status.ClientName,
+			MonitorPath:     status.MonitorPath,
+			Status:          status.Status,
+			LastSnapshot:    status.LastBackup,
+			Snapshots:       status.Snapshots,
+			RecentSnapshots: status.FileCount,
+		})
+	}
+	return clients
+}
+
+// clearPathStatus forgets the failure of a monitored path whose client
+// folders can be listed again, resolving its incident if one is open
+func (m *Monitor) clearPathStatus(monitorPath string) {
+	m.mu.Lock()
+	cs := m.state.Remove(monitorPath, monitorPath)
+	if cs != nil {
+		m.saveState()
+	}
+	m.mu.Unlock()
+
+	if cs != nil && cs.IncidentOpen {
+		key := incidentKey(BackupStatus{MonitorPath: monitorPath, FolderID: monitorPath})
+		m.sendIncident(key, func(ch incidentChannel) error {
+			return ch.Resolve(key)
+		})
+	}
+}
 
-			if err := m.telegram.SendBackupAlert(
-				status.ClientName,
-				status.FolderPath,
-				lastBackupStr,
-			); err != nil {
-				logger.Error("Failed to send backup alert for %s: %v", status.ClientName, err)
+// forgetRemovedClients drops the state of clients whose folder is gone from
+// a monitored path that could be listed, or whose monitored path was removed,
+// resolving their open incidents. Clients of paths that could not be listed
+// are kept until the path can be checked again.
+func (m *Monitor) forgetRemovedClients(listed, found map[string]bool) {
+	monitored := make(map[string]bool)
+	for _, ref := range m.config.OneDrive.MonitorPaths {
+		monitored[ref] = true
+	}
+
+	var removed []state.ClientState
+	m.mu.Lock()
+	for _, cs := range m.state.List() {
+		if found[state.ClientKey(cs.MonitorPath, cs.ClientName)] {
+			continue
+		}
+		if monitored[cs.MonitorPath] && !listed[cs.MonitorPath] {
+			continue
+		}
+		removed = append(removed, *cs)
+	}
+	m.mu.Unlock()
+	if len(removed) == 0 {
+		return
+	}
+
+	// Resolve incidents without holding the mutex, forgetting only the
+	// clients whose incidents are closed
+	var forget []state.ClientState
+	for _, cs := range removed {
+		m.log.Info("Client %s in %s is no longer monitored, forgetting it", cs.Name(), m.config.FolderPath(cs.MonitorPath))
+		if cs.IncidentOpen {
+			key := incidentKey(BackupStatus{MonitorPath: cs.MonitorPath, ClientName: cs.ClientName})
+			if !m.sendIncident(key, func(ch incidentChannel) error {
+				return ch.Resolve(key)
+			}) {
+				// Retry on the next check
+				continue
 			}
 		}
+		forget = append(forget, cs)
+	}
+	if len(forget) == 0 {
+		return
 	}
 
-	// Send summary report
-	totalClients := len(statuses)
-	if err := m.telegram.SendSummaryReport(totalClients, successCount, failedCount, failedClients); err != nil {
-		logger.Error("Failed to send summary report: %v", err)
-		return err
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, cs := range forget {
+		m.state.Remove(cs.MonitorPath, cs.ClientName)
 	}
+	m.saveState()
+}
 
-	return nil
+// classifyError returns the status of a client whose check failed with err
+func classifyError(err error) string {
+	var apiErr *onedrive.APIError
+	switch {
+	case errors.Is(err, onedrive.ErrNotARepo):
+		return templates.StatusNotARepo
+	case errors.As(err, &apiErr) && apiErr.IsAuth():
+		return templates.StatusAuthError
+	default:
+		return templates.StatusCheckError
+	}
+}
+
+// clientData converts a backup status to the template data model
+func clientData(status BackupStatus, now time.Time) templates.Client {
+	data := templates.Client{
+		Name:        status.ClientName,
+		MonitorPath: status.MonitorPath,
+		MonitorName: status.MonitorName,
+		FolderID:    status.FolderID,
+		FolderPath:  status.FolderPath,
+		DisplayName: status.ClientName,
+		HasBackup:   status.HasBackup,
+		Status:      status.Status,
+		LastBackup:  status.LastBackup,
+		FileCount:   status.FileCount,
+
+		ConsecutiveFailures: status.ConsecutiveFailures,
+		FailingSince:        status.FailingSince,
+		EscalationLevel:     status.EscalationLevel,
+		Severity:            status.Severity,
+	}
+	if !status.LastBackup.IsZero() {
+		data.Age = now.Sub(status.LastBackup)
+		data.AgeSeconds = data.Age.Seconds()
+	}
+	if status.Error != nil {
+		data.Error = status.Error.Error()
+	}
+	return data
+}
+
+// recordStatuses stores the latest check results in the persisted state
+func (m *Monitor) recordStatuses(statuses []BackupStatus) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for i, status := range statuses {
+		cs := m.state.Client(status.MonitorPath, status.stateName())
+		if cs.Status != status.Status {
+			// An acknowledgement only holds until the client's state changes
+			cs.AckedBy = ""
+			cs.AckedAt = time.Time{}
+		}
+
+		if status.HasBackup {
+			cs.ConsecutiveFailures = 0
+			cs.FailingSince = time.Time{}
+		} else {
+			cs.ConsecutiveFailures++
+			if cs.FailingSince.IsZero() {
+				cs.FailingSince = now
+			}
+		}
+
+		level, severity := escalationLevel(m.config.Escalation, cs, now)
+		if level > cs.EscalationLevel {
+			m.log.Info("Client %s escalated to level %d (%s)", status.ClientName, level, severity)
+			cs.AckedBy = ""
+			cs.AckedAt = time.Time{}
+		}
+		cs.EscalationLevel = level
+		statuses[i].EscalationLevel = level
+		statuses[i].Severity = severity
+		statuses[i].ConsecutiveFailures = cs.ConsecutiveFailures
+		statuses[i].FailingSince = cs.FailingSince
+
+		cs.DisplayName = ""
+		if status.ClientName != status.stateName() {
+			cs.DisplayName = status.ClientName
+		}
+		cs.FolderID = status.FolderID
+		cs.FolderPath = status.FolderPath
+		cs.HasBackup = status.HasBackup
+		cs.Status = status.Status
+		cs.FileCount = status.FileCount
+		cs.LastChecked = now
+		cs.LastError = ""
+		if !status.LastBackup.IsZero() {
+			cs.LastBackup = status.LastBackup
+		}
+
+		entry := state.HistoryEntry{
+			Time:      now,
+			HasBackup: status.HasBackup,
+			Status:    status.Status,
+			FileCount: status.FileCount,
+		}
+		if status.Error != nil {
+			cs.LastError = status.Error.Error()
+			entry.Error = cs.LastError
+		}
+		cs.AddHistory(entry)
+	}
+
+	m.saveState()
+}
+
+// isMuted reports whether alerts for the client are silenced or acknowledged
+func (m *Monitor) isMuted(status BackupStatus) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cs := m.state.Client(status.MonitorPath, status.stateName())
+	return cs.IsSilenced(time.Now()) || cs.IsAcknowledged()
+}
+
+// OneDriveClient returns a OneDrive client for cfg, refreshing and saving
+// the token first if it has expired
+func OneDriveClient(cfg *config.Config) (*onedrive.Client, error) {
+	if err := refreshToken(cfg, onedrive.NewAuthenticator()); err != nil {
+		return nil, err
+	}
+	return onedrive.NewClient(cfg.OneDrive.AccessToken), nil
 }
 
 // refreshTokenIfNeeded refreshes the OAuth token if it's expired
 func (m *Monitor) refreshTokenIfNeeded() error {
-	if m.config.OneDrive.TokenExpiry == 0 {
-		return fmt.Errorf("no token expiry set")
+	return refreshToken(m.config, m.onedriveAuth)
+}
+
+// refreshToken refreshes and saves the OAuth token of cfg if it's expired
+func refreshToken(cfg *config.Config, auth *onedrive.Authenticator) error {
+	if cfg.OneDrive.TokenExpiry == 0 {
+		return &onedrive.AuthError{Kind: onedrive.AuthUnknown, Description: "not logged in to OneDrive"}
 	}
 
-	expiry := time.Unix(m.config.OneDrive.TokenExpiry, 0)
+	expiry := time.Unix(cfg.OneDrive.TokenExpiry, 0)
 	if time.Now().Before(expiry.Add(-10 * time.Minute)) {
 		// Token is still valid (with 10 minute buffer)
 		return nil
 	}
 
+	// Another process may be refreshing the token at the same time, and
+	// refresh tokens are single-use: lock, then use the token it saved
+	if err := cfg.Lock(); err != nil {
+		return fmt.Errorf("failed to lock configuration: %w", err)
+	}
+	defer cfg.Unlock()
+
+	expiry = time.Unix(cfg.OneDrive.TokenExpiry, 0)
+	if time.Now().Before(expiry.Add(-10 * time.Minute)) {
+		logger.Debug("OneDrive token was refreshed by another process")
+		return nil
+	}
+
 	logger.Info("Refreshing OneDrive token...")
 
 	// Create token from stored values
 	token := &oauth2.Token{
-		AccessToken:  m.config.OneDrive.AccessToken,
-		RefreshToken: m.config.OneDrive.RefreshToken,
+		AccessToken:  cfg.OneDrive.AccessToken,
+		RefreshToken: cfg.OneDrive.RefreshToken,
 		Expiry:       expiry,
 	}
 
 	// Refresh the token
-	newToken, err := m.onedriveAuth.RefreshToken(token)
+	newToken, err := auth.RefreshToken(token)
 	if err != nil {
 		return fmt.Errorf("failed to refresh token: %w", err)
 	}
 
 	// Update configuration
-	m.config.OneDrive.AccessToken = newToken.AccessToken
-	m.config.OneDrive.RefreshToken = newToken.RefreshToken
-	m.config.OneDrive.TokenExpiry = newToken.Expiry.Unix()
+	cfg.OneDrive.AccessToken = newToken.AccessToken
+	cfg.OneDrive.RefreshToken = newToken.RefreshToken
+	cfg.OneDrive.TokenExpiry = newToken.Expiry.Unix()
+	if newToken.RefreshToken != token.RefreshToken || cfg.OneDrive.RefreshTokenIssued == 0 {
+		cfg.OneDrive.RefreshTokenIssued = time.Now().Unix()
+	}
 
 	// Save updated configuration
-	if err := m.config.Save(); err != nil {
+	if err := cfg.Save(); err != nil {
 		logger.Error("Failed to save updated token: %v", err)
 	}
 
@@ -0,0 +1,39 @@
+package monitor
+
+import (
+	"strings"
+	"testing"
+
+	"restic-backup-checker/internal/config"
+)
+
+func TestGoSafeStopsOnPanic(t *testing.T) {
+	m := testMonitor(t, &config.Config{})
+	m.stopChan = make(chan struct{})
+
+	// A second panic doesn't close stopChan again
+	m.goSafe("Periodic monitoring", func() { panic("boom") })
+	m.goSafe("Bot", func() {
+		<-m.stopChan
+		panic("late")
+	})
+	<-m.stopChan
+	m.wg.Wait()
+
+	if m.stopErr == nil || !strings.Contains(m.stopErr.Error(), "periodic monitoring panicked: boom") {
+		t.Errorf("stopErr = %v, want the first panic", m.stopErr)
+	}
+}
+
+func TestStopAfterPanic(t *testing.T) {
+	m := testMonitor(t, &config.Config{})
+	m.stopChan = make(chan struct{})
+	m.goSafe("Dashboard", func() { panic("boom") })
+	<-m.stopChan
+
+	// Stop doesn't close stopChan twice or clear the panic
+	m.Stop()
+	if m.stopErr == nil {
+		t.Error("stopErr cleared by Stop")
+	}
+}
@@ -0,0 +1,194 @@
+package monitor
+
+import (
+	"fmt"
+	"time"
+
+	"restic-backup-checker/internal/config"
+	"restic-backup-checker/internal/logger"
+	"restic-backup-checker/internal/state"
+	"restic-backup-checker/internal/templates"
+)
+
+// severityRank orders severities from least to most urgent
+var severityRank = map[string]int{
+	"info":     1,
+	"warning":  2,
+	"error":    3,
+	"critical": 4,
+}
+
+// inQuietHours reports whether now falls within a route's quiet hours
+func inQuietHours(q *config.QuietHoursConfig, now time.Time) (bool, error) {
+	if q == nil {
+		return false, nil
+	}
+
+	start, err := parseClock(q.Start)
+	if err != nil {
+		return false, fmt.Errorf("invalid quiet hours start: %w", err)
+	}
+	end, err := parseClock(q.End)
+	if err != nil {
+		return false, fmt.Errorf("invalid quiet hours end: %w", err)
+	}
+
+	loc := time.Local
+	if q.TimeZone != "" {
+		if loc, err = time.LoadLocation(q.TimeZone); err != nil {
+			return false, fmt.Errorf("invalid quiet hours time zone: %w", err)
+		}
+	}
+
+	t := now.In(loc)
+	minute := t.Hour()*60 + t.Minute()
+	if start <= end {
+		return minute >= start && minute < end, nil
+	}
+	// The window spans midnight
+	return minute >= start || minute < end, nil
+}
+
+// parseClock parses "HH:MM" into minutes after midnight
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// breaksThrough reports whether an alert of the given severity is delivered
+// despite quiet hours. The default threshold is critical, above the
+// defaultSeverity of unescalated alerts, so only escalated alerts break
+// through unless the route lowers it.
+func breaksThrough(q *config.QuietHoursConfig, severity string) bool {
+	threshold := q.BreakThrough
+	if threshold == "" {
+		threshold = "critical"
+	}
+	return severityRank[severity] >= severityRank[threshold]
+}
+
+// routeIsQuiet reports whether a route is currently in quiet hours. Invalid
+// quiet hours are logged and treated as inactive so alerts are not lost.
+func routeIsQuiet(r config.RouteConfig, now time.Time) bool {
+	quiet, err := inQuietHours(r.QuietHours, now)
+	if err != nil {
+		logger.Error("Route %s: %v", r.Name, err)
+		return false
+	}
+	return quiet
+}
+
+// queueAlert holds back an alert until the route's quiet hours end
+func (m *Monitor) queueAlert(r config.RouteConfig, data templates.Client, now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.state.Enqueue(state.QueuedAlert{Route: r.Name, Queued: now, Client: data})
+	m.saveState()
+	m.log.Info("Quiet hours on route %s: alert for %s queued", r.Name, data.Name)
+}
+
+// routeByName returns the configured route with the given name
+func (m *Monitor) routeByName(name string) (config.RouteConfig, bool) {
+	if def := m.defaultRoute(); def.Name == name {
+		return def, true
+	}
+	for _, r := range m.config.Routing.Routes {
+		if r.Name == name {
+			return r, true
+		}
+	}
+	return config.RouteConfig{}, false
+}
+
+// flushDigests delivers the queued alerts of every route whose quiet hours
+// have ended as a single digest per route. Alerts stay queued until their
+// digest was delivered, so they are retried if it fails.
+func (m *Monitor) flushDigests() {
+	m.digestMu.Lock()
+	defer m.digestMu.Unlock()
+
+	now := time.Now()
+
+	m.mu.Lock()
+	routes := m.state.QueuedRoutes()
+	m.mu.Unlock()
+
+	for _, name := range routes {
+		r, ok := m.routeByName(name)
+		if ok && routeIsQuiet(r, now) {
+			continue
+		}
+
+		m.mu.Lock()
+		queued := m.state.Queued(name)
+		m.mu.Unlock()
+		if len(queued) == 0 {
+			continue
+		}
+		until := queued[len(queued)-1].Queued
+
+		if !ok {
+			m.log.Error("Dropping %d queued alerts for removed route %s", len(queued), name)
+			m.dequeue(name, until)
+			continue
+		}
+
+		digest := templates.DigestData{Since: queued[0].Queued, Now: now}
+		latest := make(map[string]int)
+		for _, alert := range queued {
+			key := state.ClientKey(alert.Client.MonitorPath, alert.Client.Name)
+			if i, seen := latest[key]; seen {
+				digest.Alerts[i] = alert.Client
+				continue
+			}
+			latest[key] = len(digest.Alerts)
+			digest.Alerts = append(digest.Alerts, alert.Client)
+		}
+		digest.Alerts = m.stillFailing(digest.Alerts)
+		if len(digest.Alerts) == 0 {
+			m.log.Info("Quiet hours ended on route %s: all clients with queued alerts recovered", name)
+			m.dequeue(name, until)
+			continue
+		}
+
+		m.log.Info("Quiet hours ended on route %s: sending digest of %d alerts", name, len(digest.Alerts))
+		if err := m.deliver(r, templates.TypeDigest, digest, ""); err != nil {
+			m.log.Error("Failed to send digest for route %s, keeping its alerts queued: %v", name, err)
+			continue
+		}
+		m.dequeue(name, until)
+	}
+}
+
+// stillFailing drops the alerts of clients whose backup is fresh again, so a
+// digest doesn't report failures that recovered during quiet hours
+func (m *Monitor) stillFailing(alerts []templates.Client) []templates.Client {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var failing []templates.Client
+	for _, c := range alerts {
+		name := c.Name
+		if c.FolderID == c.MonitorPath {
+			name = c.MonitorPath // a monitored path that could not be listed
+		}
+		if cs := m.state.Get(c.MonitorPath, name); cs != nil && cs.HasBackup {
+			continue
+		}
+		failing = append(failing, c)
+	}
+	return failing
+}
+
+// dequeue removes the alerts queued for a route up to until and saves the state
+func (m *Monitor) dequeue(route string, until time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.state.Dequeue(route, until)
+	m.saveState()
+}
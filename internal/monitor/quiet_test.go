@@ -0,0 +1,127 @@
+package monitor
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"restic-backup-checker/internal/config"
+	"restic-backup-checker/internal/logger"
+	"restic-backup-checker/internal/state"
+	"restic-backup-checker/internal/templates"
+)
+
+func TestInQuietHours(t *testing.T) {
+	at := func(hour, minute int) time.Time {
+		return time.Date(2024, 5, 10, hour, minute, 0, 0, time.UTC)
+	}
+	overnight := &config.QuietHoursConfig{Start: "22:00", End: "07:00", TimeZone: "UTC"}
+	daytime := &config.QuietHoursConfig{Start: "09:00", End: "17:30", TimeZone: "UTC"}
+	berlin := &config.QuietHoursConfig{Start: "22:00", End: "07:00", TimeZone: "Europe/Berlin"}
+
+	tests := []struct {
+		name  string
+		quiet *config.QuietHoursConfig
+		now   time.Time
+		want  bool
+	}{
+		{"no quiet hours", nil, at(23, 0), false},
+		{"overnight before start", overnight, at(21, 59), false},
+		{"overnight at start", overnight, at(22, 0), true},
+		{"overnight after midnight", overnight, at(0, 30), true},
+		{"overnight before end", overnight, at(6, 59), true},
+		{"overnight at end", overnight, at(7, 0), false},
+		{"daytime inside", daytime, at(12, 0), true},
+		{"daytime at end", daytime, at(17, 30), false},
+		{"daytime before start", daytime, at(8, 59), false},
+		{"time zone inside", berlin, at(21, 0), true},    // 23:00 in Berlin
+		{"time zone outside", berlin, at(19, 30), false}, // 21:30 in Berlin
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := inQuietHours(tt.quiet, tt.now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("inQuietHours() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInQuietHoursInvalid(t *testing.T) {
+	for _, q := range []*config.QuietHoursConfig{
+		{Start: "25:00", End: "07:00"},
+		{Start: "22:00", End: "7"},
+		{Start: "22:00", End: "07:00", TimeZone: "Mars/Olympus"},
+	} {
+		if _, err := inQuietHours(q, time.Now()); err == nil {
+			t.Errorf("inQuietHours(%+v) accepted invalid quiet hours", *q)
+		}
+	}
+}
+
+func TestBreaksThrough(t *testing.T) {
+	tests := []struct {
+		threshold string
+		severity  string
+		want      bool
+	}{
+		{"", "error", false},
+		{"", "critical", true},
+		{"error", "error", true},
+		{"error", "warning", false},
+		{"info", "", false},
+	}
+	for _, tt := range tests {
+		q := &config.QuietHoursConfig{BreakThrough: tt.threshold}
+		if got := breaksThrough(q, tt.severity); got != tt.want {
+			t.Errorf("breaksThrough(%q, %q) = %v, want %v", tt.threshold, tt.severity, got, tt.want)
+		}
+	}
+}
+
+func TestFlushDigestsKeepsAlertsUntilDelivered(t *testing.T) {
+	store, err := state.Load(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{}
+	cfg.Telegram.ChatID = 1 // no Telegram client, so delivery fails
+	m := &Monitor{config: cfg, state: store, templates: templates.Default(), log: logger.With()}
+
+	store.Enqueue(state.QueuedAlert{Route: "default", Queued: time.Now(), Client: templates.Client{Name: "web01"}})
+	m.flushDigests()
+	if n := len(store.Queued("default")); n != 1 {
+		t.Fatalf("%d alerts queued after a failed digest, want 1", n)
+	}
+
+	cfg.Telegram.ChatID = 0 // no destinations, so delivery succeeds
+	m.flushDigests()
+	if n := len(store.Queued("default")); n != 0 {
+		t.Fatalf("%d alerts queued after a delivered digest, want 0", n)
+	}
+}
+
+func TestFlushDigestsSkipsRecoveredClients(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Telegram.ChatID = 1 // no Telegram client, so a digest would fail
+	m := testMonitor(t, cfg)
+	store := m.state
+	queued := time.Now().Add(-2 * time.Hour)
+	store.Enqueue(state.QueuedAlert{Route: "default", Queued: queued, Client: templates.Client{Name: "web01", MonitorPath: "F1", FolderID: "A"}})
+	store.Enqueue(state.QueuedAlert{Route: "default", Queued: queued, Client: templates.Client{Name: "db01", MonitorPath: "F1", FolderID: "B"}})
+	store.Client("F1", "web01").HasBackup = true // recovered after the alert was queued
+
+	if got := m.stillFailing([]templates.Client{{Name: "web01", MonitorPath: "F1"}, {Name: "db01", MonitorPath: "F1"}}); len(got) != 1 || got[0].Name != "db01" {
+		t.Errorf("stillFailing() = %+v, want only db01", got)
+	}
+
+	store.Client("F1", "db01").HasBackup = true
+	m.flushDigests()
+	if n := len(store.Queued("default")); n != 0 {
+		t.Errorf("%d alerts queued after every client recovered, want 0", n)
+	}
+}
@@ -0,0 +1,288 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"time"
+
+	"restic-backup-checker/internal/config"
+	"restic-backup-checker/internal/state"
+	"restic-backup-checker/internal/templates"
+	"restic-backup-checker/internal/webhook"
+)
+
+// routeTarget is a notification route with the clients it applies to
+type routeTarget struct {
+	route    config.RouteConfig
+	statuses []BackupStatus
+}
+
+// defaultRoute returns the route for clients matched by no configured route,
+// falling back to the Telegram chat from setup
+func (m *Monitor) defaultRoute() config.RouteConfig {
+	r := m.config.Routing.Default
+	if r.Name == "" {
+		r.Name = "default"
+	}
+	if !r.HasDestinations() && m.config.Telegram.ChatID != 0 {
+		r.ChatIDs = []int64{m.config.Telegram.ChatID}
+	}
+	return r
+}
+
+// routeChatIDs returns the Telegram chats of all routes, which may use alert buttons
+func (m *Monitor) routeChatIDs() []int64 {
+	ids := append([]int64(nil), m.config.Routing.Default.ChatIDs...)
+	for _, r := range m.config.Routing.Routes {
+		ids = append(ids, r.ChatIDs...)
+	}
+	return ids
+}
+
+// chatAccess describes the clients a Telegram chat may see and act on
+type chatAccess struct {
+	all    bool                 // every client
+	routes []config.RouteConfig // otherwise the clients of these routes
+}
+
+// chatAccess returns the access of a chat. The configured chat, the chats of
+// the default route and command chats on no route see every client; the
+// chats of other routes only see the clients routed to them.
+func (m *Monitor) chatAccess(chatID int64) chatAccess {
+	if chatID == m.config.Telegram.ChatID || containsChat(m.defaultRoute().ChatIDs, chatID) {
+		return chatAccess{all: true}
+	}
+
+	var access chatAccess
+	for _, r := range m.config.Routing.Routes {
+		if containsChat(r.ChatIDs, chatID) {
+			access.routes = append(access.routes, r)
+		}
+	}
+	if len(access.routes) == 0 && containsChat(m.config.Telegram.AllowedChatIDs, chatID) {
+		access.all = true
+	}
+	return access
+}
+
+// allows reports whether a client is routed to the chat, directly or through
+// escalation
+func (a chatAccess) allows(steps []config.EscalationStep, cs *state.ClientState) bool {
+	if a.all {
+		return true
+	}
+
+	status := BackupStatus{MonitorPath: cs.MonitorPath, ClientName: cs.Name()}
+	escalated := escalationRoutes(steps, cs.EscalationLevel)
+	for _, r := range a.routes {
+		if routeMatches(r, status) {
+			return true
+		}
+		for _, name := range escalated {
+			if name == r.Name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// visibleClients returns the clients among clients the chat may see. Callers
+// must hold m.mu.
+func (m *Monitor) visibleClients(chatID int64, clients []*state.ClientState) []*state.ClientState {
+	access := m.chatAccess(chatID)
+	var visible []*state.ClientState
+	for _, cs := range clients {
+		if access.allows(m.config.Escalation, cs) {
+			visible = append(visible, cs)
+		}
+	}
+	return visible
+}
+
+// containsChat reports whether chatID is among ids
+func containsChat(ids []int64, chatID int64) bool {
+	for _, id := range ids {
+		if id == chatID {
+			return true
+		}
+	}
+	return false
+}
+
+// routeMatches reports whether a route applies to a client
+func routeMatches(r config.RouteConfig, status BackupStatus) bool {
+	for _, p := range r.Paths {
+		if p == status.MonitorPath {
+			return true
+		}
+	}
+	for _, pattern := range r.Clients {
+		if ok, _ := path.Match(pattern, status.ClientName); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// groupByRoute assigns each client to every route it matches, or to the
+// default route if it matches none. Escalated clients are also assigned to
+// the routes of the escalation steps they reached.
+func (m *Monitor) groupByRoute(statuses []BackupStatus) []routeTarget {
+	targets := make([]routeTarget, len(m.config.Routing.Routes))
+	for i, r := range m.config.Routing.Routes {
+		targets[i].route = r
+	}
+	fallback := routeTarget{route: m.defaultRoute()}
+
+	for _, status := range statuses {
+		escalated := make(map[string]bool)
+		for _, name := range escalationRoutes(m.config.Escalation, status.EscalationLevel) {
+			escalated[name] = true
+		}
+
+		matched := false
+		for i := range targets {
+			if routeMatches(targets[i].route, status) {
+				targets[i].statuses = append(targets[i].statuses, status)
+				matched = true
+			} else if escalated[targets[i].route.Name] {
+				targets[i].statuses = append(targets[i].statuses, status)
+			}
+		}
+		if !matched || escalated[fallback.route.Name] {
+			fallback.statuses = append(fallback.statuses, status)
+		}
+	}
+
+	// The default route always gets a summary; other routes only if they
+	// have clients
+	result := []routeTarget{fallback}
+	for _, t := range targets {
+		if len(t.statuses) > 0 {
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
+// sendNotifications sends alerts and a filtered summary report to every route
+func (m *Monitor) sendNotifications(statuses []BackupStatus, run templates.Run) error {
+	now := time.Now()
+
+	muted := make(map[string]bool)
+	for _, status := range statuses {
+		if !status.HasBackup && m.isMuted(status) {
+			muted[incidentKey(status)] = true
+		}
+	}
+
+	var errs []error
+	for _, target := range m.groupByRoute(statuses) {
+		quiet := routeIsQuiet(target.route, now)
+		summary := templates.SummaryData{
+			Run: templates.Run{Started: run.Started, Duration: run.Duration},
+			Now: now,
+		}
+
+		// Send individual alerts for failed backups
+		for _, status := range target.statuses {
+			data := clientData(status, now)
+			summary.Clients = append(summary.Clients, data)
+			summary.Run.Count(status.Status)
+			if status.HasBackup {
+				continue
+			}
+			summary.Failed = append(summary.Failed, data)
+
+			if muted[incidentKey(status)] {
+				continue
+			}
+
+			if quiet && !breaksThrough(target.route.QuietHours, status.Severity) {
+				m.queueAlert(target.route, data, now)
+				continue
+			}
+
+			key := actionKey(status.MonitorPath, status.stateName())
+			if err := m.deliver(target.route, templates.TypeAlert, templates.AlertData{Client: data, Now: now}, key); err != nil {
+				errs = append(errs, fmt.Errorf("alert for %s: %w", status.ClientName, err))
+			}
+		}
+
+		// Send summary report; during quiet hours it is superseded by the digest
+		if quiet {
+			continue
+		}
+		if err := m.deliver(target.route, templates.TypeSummary, summary, ""); err != nil {
+			errs = append(errs, fmt.Errorf("summary for route %s: %w", target.route.Name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// deliver renders a notification for each channel of a route and sends it to
+// the route's destinations. actionKey attaches alert buttons to Telegram messages.
+func (m *Monitor) deliver(r config.RouteConfig, kind string, data interface{}, actionKey string) error {
+	var errs []error
+	fail := func(channel string, err error) {
+		m.log.Error("Failed to send %s via %s (route %s): %v", kind, channel, r.Name, err)
+		if m.metrics != nil {
+			m.metrics.SendFailed(channel)
+		}
+		errs = append(errs, err)
+	}
+
+	if len(r.ChatIDs) > 0 {
+		message, err := m.templates.Render(kind, "telegram", data)
+		switch {
+		case err != nil:
+			fail("telegram", err)
+		case m.telegram == nil:
+			fail("telegram", fmt.Errorf("telegram client not initialized"))
+		default:
+			for _, chatID := range r.ChatIDs {
+				if kind == templates.TypeAlert {
+					err = m.telegram.SendBackupAlert(chatID, message, actionKey)
+				} else {
+					err = m.telegram.SendSummaryReport(chatID, message)
+				}
+				if err != nil {
+					fail("telegram", err)
+				}
+			}
+		}
+	}
+
+	if len(r.Emails) > 0 {
+		rendered, err := m.templates.Render(kind, "email", data)
+		switch {
+		case err != nil:
+			fail("email", err)
+		case m.email == nil:
+			fail("email", fmt.Errorf("SMTP is not configured"))
+		default:
+			subject, body := templates.SplitSubject(rendered, "[restic-backup-checker] Backup "+kind)
+			if err := m.email.Send(r.Emails, subject, body); err != nil {
+				fail("email", err)
+			}
+		}
+	}
+
+	if len(r.Webhooks) > 0 {
+		text, err := m.templates.Render(kind, "webhook", data)
+		if err != nil {
+			fail("webhook", err)
+		} else {
+			for _, url := range r.Webhooks {
+				if err := m.webhook.Send(url, webhook.Payload{Type: kind, Text: text, Data: data}); err != nil {
+					fail("webhook", err)
+				}
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
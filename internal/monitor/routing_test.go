@@ -0,0 +1,52 @@
+package monitor
+
+import (
+	"testing"
+
+	"restic-backup-checker/internal/config"
+	"restic-backup-checker/internal/state"
+)
+
+func TestChatAccess(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Telegram.ChatID = 1
+	cfg.Telegram.AllowedChatIDs = []int64{2, 5}
+	cfg.Routing.Default.ChatIDs = []int64{3}
+	cfg.Routing.Routes = []config.RouteConfig{
+		{Name: "acme", Clients: []string{"acme-*"}, ChatIDs: []int64{4, 5}},
+		{Name: "folder", Paths: []string{"F1"}, ChatIDs: []int64{6}},
+		{Name: "admins", ChatIDs: []int64{7}},
+	}
+	cfg.Escalation = []config.EscalationStep{{AfterFailures: 3, Route: "admins"}}
+	m := &Monitor{config: cfg}
+
+	acme := &state.ClientState{ClientName: "acme-web", MonitorPath: "F0"}
+	other := &state.ClientState{ClientName: "db01", MonitorPath: "F1"}
+	escalated := &state.ClientState{ClientName: "web01", MonitorPath: "F0", EscalationLevel: 1}
+
+	tests := []struct {
+		name   string
+		chatID int64
+		want   []bool // access to acme, other, escalated
+	}{
+		{"configured chat", 1, []bool{true, true, true}},
+		{"command chat on no route", 2, []bool{true, true, true}},
+		{"default route chat", 3, []bool{true, true, true}},
+		{"client route chat", 4, []bool{true, false, false}},
+		{"command chat on a route", 5, []bool{true, false, false}},
+		{"path route chat", 6, []bool{false, true, false}},
+		{"escalation route chat", 7, []bool{false, false, true}},
+		{"unknown chat", 8, []bool{false, false, false}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			access := m.chatAccess(tt.chatID)
+			for i, cs := range []*state.ClientState{acme, other, escalated} {
+				if got := access.allows(cfg.Escalation, cs); got != tt.want[i] {
+					t.Errorf("allows(%s) = %v, want %v", cs.ClientName, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
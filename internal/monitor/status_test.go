@@ -0,0 +1,113 @@
+package monitor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"restic-backup-checker/internal/config"
+	"restic-backup-checker/internal/logger"
+	"restic-backup-checker/internal/onedrive"
+	"restic-backup-checker/internal/templates"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"not a repository", fmt.Errorf("%w in folder F1", onedrive.ErrNotARepo), templates.StatusNotARepo},
+		{"unauthorized", fmt.Errorf("failed to get subfolders: %w", &onedrive.APIError{StatusCode: http.StatusUnauthorized}), templates.StatusAuthError},
+		{"forbidden", &onedrive.APIError{StatusCode: http.StatusForbidden}, templates.StatusAuthError},
+		{"server error", &onedrive.APIError{StatusCode: http.StatusServiceUnavailable}, templates.StatusCheckError},
+		{"network", errors.New("connection refused"), templates.StatusCheckError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyError(tt.err); got != tt.want {
+				t.Errorf("classifyError() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// childrenStub serves the children of drive items by item ID; an item
+// mapped to an HTTP status code fails with that status
+func childrenStub(t *testing.T, children map[string]interface{}) *onedrive.Client {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/me/drive/items/"), "/children")
+		switch items := children[id].(type) {
+		case []map[string]interface{}:
+			json.NewEncoder(w).Encode(map[string]interface{}{"value": items})
+		case int:
+			http.Error(w, "failed", items)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return onedrive.NewClient("token").WithBaseURL(srv.URL)
+}
+
+func TestCheckClientBackup(t *testing.T) {
+	now := time.Now()
+	snapshots := []map[string]interface{}{{"id": "S", "name": "snapshots", "folder": map[string]interface{}{}}}
+	snapshot := func(age time.Duration) map[string]interface{} {
+		return map[string]interface{}{"id": "F", "name": "abc", "file": map[string]interface{}{}, "createdDateTime": now.Add(-age).Format(time.RFC3339)}
+	}
+
+	tests := []struct {
+		name          string
+		children      map[string]interface{}
+		want          string
+		wantHasBackup bool
+		wantLast      bool
+	}{
+		{"recent snapshot", map[string]interface{}{"C": snapshots, "S": []map[string]interface{}{snapshot(72 * time.Hour), snapshot(time.Hour)}}, templates.StatusOK, true, true},
+		{"old snapshots", map[string]interface{}{"C": snapshots, "S": []map[string]interface{}{snapshot(72 * time.Hour)}}, templates.StatusStale, false, true},
+		{"no snapshots", map[string]interface{}{"C": snapshots, "S": []map[string]interface{}{}}, templates.StatusNoSnapshots, false, false},
+		{"not a repository", map[string]interface{}{"C": []map[string]interface{}{}}, templates.StatusNotARepo, false, false},
+		{"unauthorized", map[string]interface{}{"C": http.StatusUnauthorized}, templates.StatusAuthError, false, false},
+		{"server error", map[string]interface{}{"C": snapshots, "S": http.StatusServiceUnavailable}, templates.StatusCheckError, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := testMonitor(t, &config.Config{})
+			status := m.checkClientBackup(logger.With(), childrenStub(t, tt.children), "F1", "C", "web01")
+
+			if status.Status != tt.want {
+				t.Errorf("Status = %s, want %s (error %v)", status.Status, tt.want, status.Error)
+			}
+			if (status.Error != nil) != (tt.want == templates.StatusCheckError || tt.want == templates.StatusAuthError || tt.want == templates.StatusNotARepo) {
+				t.Errorf("Error = %v", status.Error)
+			}
+			if status.HasBackup != tt.wantHasBackup {
+				t.Errorf("HasBackup = %v, want %v", status.HasBackup, tt.wantHasBackup)
+			}
+			if status.LastBackup.IsZero() == tt.wantLast {
+				t.Errorf("LastBackup = %v", status.LastBackup)
+			}
+		})
+	}
+}
+
+func TestRunCount(t *testing.T) {
+	var run templates.Run
+	for _, status := range []string{templates.StatusOK, templates.StatusStale, templates.StatusNoSnapshots,
+		templates.StatusNotARepo, templates.StatusCheckError, templates.StatusAuthError, templates.StatusOK} {
+		run.Count(status)
+	}
+	want := templates.Run{Total: 7, Successful: 2, Failed: 5, Stale: 1, NoSnapshots: 1, NotARepo: 1, CheckErrors: 1, AuthErrors: 1}
+	if run != want {
+		t.Errorf("Run = %+v, want %+v", run, want)
+	}
+}
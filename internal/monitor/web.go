@@ -0,0 +1,72 @@
+package monitor
+
+import (
+	"net/http"
+	"time"
+
+	"restic-backup-checker/internal/state"
+	"restic-backup-checker/internal/templates"
+	"restic-backup-checker/internal/web"
+)
+
+// Clients returns a copy of the state of all clients, sorted by name
+func (m *Monitor) Clients() []state.ClientState {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	list := m.state.List()
+	clients := make([]state.ClientState, 0, len(list))
+	for _, cs := range list {
+		c := *cs
+		c.History = append([]state.HistoryEntry(nil), cs.History...)
+		clients = append(clients, c)
+	}
+	return clients
+}
+
+// Runs returns a copy of the recorded check runs, oldest first
+func (m *Monitor) Runs() []state.RunRecord {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]state.RunRecord(nil), m.state.Runs...)
+}
+
+// recordRun remembers a check run for the runs API
+func (m *Monitor) recordRun(id string, started time.Time, run templates.Run, err error) {
+	run.Started = started
+	if run.Duration == 0 {
+		run.Duration = time.Since(started)
+	}
+
+	record := state.RunRecord{ID: id, Run: run}
+	if err != nil {
+		record.Error = err.Error()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.state.AddRun(record)
+	m.saveState()
+}
+
+// serveWeb runs the dashboard until the monitor stops. The metrics are
+// served on the same listener if it uses the metrics listen address.
+func (m *Monitor) serveWeb() {
+	var metricsHandler http.Handler
+	if m.metrics != nil && m.config.Metrics.Listen == m.config.Web.Listen {
+		metricsHandler = m.metrics.Handler()
+	}
+
+	server, err := web.New(m.config.Web, m, metricsHandler)
+	if err != nil {
+		m.log.Error("Failed to create dashboard: %v", err)
+		return
+	}
+
+	m.log.Info("Serving dashboard on %s", m.config.Web.Listen)
+	if err := server.Serve(m.stopChan); err != nil {
+		m.log.Error("Dashboard stopped: %v", err)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 
 	"golang.org/x/oauth2"
@@ -16,6 +17,9 @@ const (
 	PublicClientID = "d3590ed6-52b3-4102-aeff-aad2292ab01c" // Microsoft Graph PowerShell public client
 	DeviceCodeURL  = "https://login.microsoftonline.com/common/oauth2/v2.0/devicecode"
 	TokenURL       = "https://login.microsoftonline.com/common/oauth2/v2.0/token"
+
+	// RefreshTokenLifetime is how long a refresh token stays valid without use
+	RefreshTokenLifetime = 90 * 24 * time.Hour
 )
 
 // Authenticator handles OneDrive OAuth2 authentication using device code flow
@@ -45,6 +49,63 @@ type TokenResponse struct {
 	ErrorDescription string `json:"error_description"`
 }
 
+// AuthErrorKind classifies why authentication with OneDrive failed
+type AuthErrorKind string
+
+const (
+	// AuthInvalidGrant means the refresh token was revoked or has expired
+	AuthInvalidGrant AuthErrorKind = "invalid_grant"
+	// AuthConsentRequired means the user or an admin must consent again
+	AuthConsentRequired AuthErrorKind = "consent_required"
+	// AuthNetwork means the token endpoint could not be reached
+	AuthNetwork AuthErrorKind = "network"
+	// AuthUnknown means the token could not be obtained for another reason,
+	// e.g. the stored token could not be read or saved
+	AuthUnknown AuthErrorKind = "unknown"
+)
+
+// AuthError is returned when a token cannot be obtained
+type AuthError struct {
+	Kind        AuthErrorKind
+	Description string
+	Err         error
+}
+
+// Error implements the error interface
+func (e *AuthError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %v", e.Kind, e.Err)
+	}
+	return fmt.Sprintf("%s: %s", e.Kind, e.Description)
+}
+
+// Unwrap returns the underlying error
+func (e *AuthError) Unwrap() error {
+	return e.Err
+}
+
+// NeedsLogin reports whether the user must run login again to recover
+func (e *AuthError) NeedsLogin() bool {
+	return e.Kind == AuthInvalidGrant || e.Kind == AuthConsentRequired
+}
+
+// classifyTokenError turns an error response of the token endpoint into an AuthError
+func classifyTokenError(tokenResp TokenResponse) *AuthError {
+	kind := AuthInvalidGrant
+	switch {
+	case tokenResp.Error == "consent_required", tokenResp.Error == "interaction_required",
+		strings.Contains(tokenResp.ErrorDescription, "AADSTS65001"):
+		kind = AuthConsentRequired
+	case tokenResp.Error == "temporarily_unavailable":
+		kind = AuthNetwork
+	}
+
+	return &AuthError{
+		Kind:        kind,
+		Description: fmt.Sprintf("%s - %s", tokenResp.Error, tokenResp.ErrorDescription),
+	}
+}
+
 // NewAuthenticator creates a new OneDrive authenticator using device code flow
 func NewAuthenticator() *Authenticator {
 	return &Authenticator{
@@ -151,10 +212,10 @@ func (a *Authenticator) pollForToken(deviceCode *DeviceCodeResponse) (*oauth2.To
 	return nil, fmt.Errorf("authentication timeout")
 }
 
-// RefreshToken refreshes an expired OAuth2 token
+// RefreshToken refreshes an expired OAuth2 token. Failures are returned as *AuthError.
 func (a *Authenticator) RefreshToken(token *oauth2.Token) (*oauth2.Token, error) {
 	if token.RefreshToken == "" {
-		return nil, fmt.Errorf("no refresh token available")
+		return nil, &AuthError{Kind: AuthUnknown, Description: "no refresh token available"}
 	}
 
 	data := url.Values{}
@@ -165,21 +226,25 @@ func (a *Authenticator) RefreshToken(token *oauth2.Token) (*oauth2.Token, error)
 
 	resp, err := a.httpClient.PostForm(TokenURL, data)
 	if err != nil {
-		return nil, fmt.Errorf("failed to refresh token: %w", err)
+		return nil, &AuthError{Kind: AuthNetwork, Err: fmt.Errorf("failed to refresh token: %w", err)}
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("token refresh failed with status %d", resp.StatusCode)
+	if resp.StatusCode >= 500 {
+		return nil, &AuthError{Kind: AuthNetwork, Err: fmt.Errorf("token refresh failed with status %d", resp.StatusCode)}
 	}
 
 	var tokenResp TokenResponse
 	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
-		return nil, fmt.Errorf("failed to decode token response: %w", err)
+		return nil, &AuthError{Kind: AuthNetwork, Err: fmt.Errorf("failed to decode token response (status %d): %w", resp.StatusCode, err)}
 	}
 
 	if tokenResp.Error != "" {
-		return nil, fmt.Errorf("token refresh failed: %s - %s", tokenResp.Error, tokenResp.ErrorDescription)
+		return nil, classifyTokenError(tokenResp)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &AuthError{Kind: AuthUnknown, Description: fmt.Sprintf("token refresh failed with status %d", resp.StatusCode)}
 	}
 
 	return &oauth2.Token{
@@ -0,0 +1,82 @@
+package onedrive
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"golang.org/x/oauth2"
+)
+
+// roundTripFunc answers HTTP requests without a network
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestRefreshTokenErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		err    error
+		want   AuthErrorKind
+	}{
+		{name: "revoked", status: 400, body: `{"error":"invalid_grant","error_description":"AADSTS700082: expired"}`, want: AuthInvalidGrant},
+		{name: "consent", status: 400, body: `{"error":"invalid_grant","error_description":"AADSTS65001: no consent"}`, want: AuthConsentRequired},
+		{name: "interaction", status: 400, body: `{"error":"interaction_required"}`, want: AuthConsentRequired},
+		{name: "unavailable", status: 400, body: `{"error":"temporarily_unavailable"}`, want: AuthNetwork},
+		{name: "server error", status: 503, body: `busy`, want: AuthNetwork},
+		{name: "unreachable", err: errors.New("connection refused"), want: AuthNetwork},
+		{name: "garbled", status: 400, body: `<html>`, want: AuthNetwork},
+		{name: "unexpected status", status: 302, body: `{}`, want: AuthUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &Authenticator{httpClient: &http.Client{Transport: roundTripFunc(func(*http.Request) (*http.Response, error) {
+				if tt.err != nil {
+					return nil, tt.err
+				}
+				return &http.Response{StatusCode: tt.status, Body: io.NopCloser(strings.NewReader(tt.body))}, nil
+			})}}
+
+			_, err := a.RefreshToken(&oauth2.Token{RefreshToken: "refresh"})
+			var authErr *AuthError
+			if !errors.As(err, &authErr) {
+				t.Fatalf("RefreshToken() error = %v, want an *AuthError", err)
+			}
+			if authErr.Kind != tt.want {
+				t.Errorf("Kind = %s, want %s", authErr.Kind, tt.want)
+			}
+		})
+	}
+}
+
+func TestRefreshTokenMissing(t *testing.T) {
+	_, err := (&Authenticator{}).RefreshToken(&oauth2.Token{})
+	var authErr *AuthError
+	if !errors.As(err, &authErr) || authErr.NeedsLogin() {
+		t.Errorf("RefreshToken() error = %v, want an error not asking for a login", err)
+	}
+}
+
+func TestNeedsLogin(t *testing.T) {
+	tests := []struct {
+		kind AuthErrorKind
+		want bool
+	}{
+		{AuthInvalidGrant, true},
+		{AuthConsentRequired, true},
+		{AuthNetwork, false},
+		{AuthUnknown, false},
+	}
+	for _, tt := range tests {
+		if got := (&AuthError{Kind: tt.kind}).NeedsLogin(); got != tt.want {
+			t.Errorf("NeedsLogin(%s) = %v, want %v", tt.kind, got, tt.want)
+		}
+	}
+}
@@ -2,16 +2,39 @@ package onedrive
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 )
 
+// ErrNotARepo is returned when a client folder has no snapshots subfolder
+var ErrNotARepo = errors.New("snapshots folder not found")
+
+// APIError is returned when the Graph API answers with an error status
+type APIError struct {
+	StatusCode int
+}
+
+// Error implements the error interface
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API request failed with status %d", e.StatusCode)
+}
+
+// IsAuth returns true if the request was rejected as unauthenticated or forbidden
+func (e *APIError) IsAuth() bool {
+	return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+}
+
 // Client represents a OneDrive API client
 type Client struct {
 	accessToken string
 	baseURL     string
 	httpClient  *http.Client
+	observe     func(code string)
 }
 
 // Folder represents a OneDrive folder
@@ -19,6 +42,18 @@ type Folder struct {
 	ID   string `json:"id"`
 	Name string `json:"name"`
 	Size int64  `json:"size"`
+	Path string `json:"-"` // path below the drive root, set by GetFolder and GetFolderByPath
+}
+
+// driveItem is a single item as returned by the Graph API
+type driveItem struct {
+	ID              string    `json:"id"`
+	Name            string    `json:"name"`
+	Size            int64     `json:"size"`
+	Folder          *struct{} `json:"folder"`
+	ParentReference struct {
+		Path string `json:"path"` // percent-encoded, e.g. /drive/root:/My%20Backups
+	} `json:"parentReference"`
 }
 
 // FileInfo represents a OneDrive file
@@ -44,6 +79,19 @@ func NewClient(accessToken string) *Client {
 	}
 }
 
+// WithObserver sets a function called with the HTTP status code of every
+// API response, or "error" if the request failed without one
+func (c *Client) WithObserver(observe func(code string)) *Client {
+	c.observe = observe
+	return c
+}
+
+// WithBaseURL sets the Graph API endpoint, e.g. to test against a local stub
+func (c *Client) WithBaseURL(baseURL string) *Client {
+	c.baseURL = strings.TrimSuffix(baseURL, "/")
+	return c
+}
+
 // GetTopLevelFolders retrieves top-level folders from OneDrive
 func (c *Client) GetTopLevelFolders() ([]Folder, error) {
 	url := fmt.Sprintf("%s/me/drive/root/children", c.baseURL)
@@ -82,6 +130,79 @@ func (c *Client) GetTopLevelFolders() ([]Folder, error) {
 	return folders, nil
 }
 
+// GetFolder retrieves a folder by ID
+func (c *Client) GetFolder(folderID string) (*Folder, error) {
+	return c.getFolder(fmt.Sprintf("%s/me/drive/items/%s?$select=%s", c.baseURL, url.PathEscape(folderID), folderFields))
+}
+
+// ResolveFolder retrieves a folder by path, as accepted by ParsePath, or by ID
+func (c *Client) ResolveFolder(ref string) (*Folder, error) {
+	if folderPath, ok := ParsePath(ref); ok {
+		return c.GetFolderByPath(folderPath)
+	}
+	return c.GetFolder(ref)
+}
+
+// ParsePath returns the path below the drive root of a folder reference given
+// as a path, e.g. /Backups/servers or /drive/root:/Backups/servers:, and
+// whether the reference is a path rather than an item ID
+func ParsePath(ref string) (string, bool) {
+	if !strings.HasPrefix(ref, "/") && !strings.HasPrefix(ref, "drive/root:") {
+		return "", false
+	}
+
+	folderPath := strings.TrimPrefix(ref, "/")
+	if rest, ok := strings.CutPrefix(folderPath, "drive/root:"); ok {
+		folderPath = strings.TrimSuffix(rest, ":")
+	}
+	return "/" + strings.Trim(folderPath, "/"), true
+}
+
+// GetFolderByPath retrieves a folder by its path below the drive root, e.g.
+// /Backups/servers
+func (c *Client) GetFolderByPath(folderPath string) (*Folder, error) {
+	folderPath = strings.Trim(folderPath, "/")
+	if folderPath == "" {
+		return c.getFolder(fmt.Sprintf("%s/me/drive/root", c.baseURL))
+	}
+
+	segments := strings.Split(folderPath, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return c.getFolder(fmt.Sprintf("%s/me/drive/root:/%s:?$select=%s", c.baseURL, strings.Join(segments, "/"), folderFields))
+}
+
+// folderFields are the drive item fields getFolder needs
+const folderFields = "id,name,size,folder,parentReference"
+
+// getFolder retrieves a single drive item and checks that it is a folder
+func (c *Client) getFolder(itemURL string) (*Folder, error) {
+	resp, err := c.makeRequest("GET", itemURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var item driveItem
+	if err := json.NewDecoder(resp.Body).Decode(&item); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if item.Folder == nil {
+		return nil, fmt.Errorf("%s is not a folder", item.Name)
+	}
+
+	folder := &Folder{ID: item.ID, Name: item.Name, Size: item.Size, Path: "/"}
+	if parent, ok := strings.CutPrefix(item.ParentReference.Path, "/drive/root:"); ok {
+		// The parent path is percent-encoded, e.g. /drive/root:/My%20Backups
+		if unescaped, err := url.PathUnescape(parent); err == nil {
+			parent = unescaped
+		}
+		folder.Path = strings.TrimSuffix(parent, "/") + "/" + item.Name
+	}
+	return folder, nil
+}
+
 // GetFolderContents retrieves contents of a specific folder
 func (c *Client) GetFolderContents(folderID string) ([]FileInfo, error) {
 	url := fmt.Sprintf("%s/me/drive/items/%s/children", c.baseURL, folderID)
@@ -195,7 +316,7 @@ func (c *Client) GetAllSnapshots(folderID string) ([]FileInfo, error) {
 	}
 
 	if snapshotsFolderID == "" {
-		return nil, fmt.Errorf("snapshots folder not found in folder %s. Available subfolders: %v", folderID, folderNames)
+		return nil, fmt.Errorf("%w in folder %s. Available subfolders: %v", ErrNotARepo, folderID, folderNames)
 	}
 
 	// Get all files in snapshots folder
@@ -240,12 +361,20 @@ func (c *Client) makeRequest(method, url string, body interface{}) (*http.Respon
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := c.httpClient.Do(req)
+	if c.observe != nil {
+		if err != nil {
+			c.observe("error")
+		} else {
+			c.observe(strconv.Itoa(resp.StatusCode))
+		}
+	}
 	if err != nil {
 		return nil, fmt.Errorf("request failed: %w", err)
 	}
 
 	if resp.StatusCode >= 400 {
-		return nil, fmt.Errorf("API request failed with status %d", resp.StatusCode)
+		resp.Body.Close()
+		return nil, &APIError{StatusCode: resp.StatusCode}
 	}
 
 	return resp, nil
@@ -0,0 +1,74 @@
+package onedrive
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		ref      string
+		wantPath string
+		wantOK   bool
+	}{
+		{"/Backups/servers", "/Backups/servers", true},
+		{"/Backups/servers/", "/Backups/servers", true},
+		{"/", "/", true},
+		{"/drive/root:/Backups/servers:", "/Backups/servers", true},
+		{"drive/root:/Backups/servers:", "/Backups/servers", true},
+		{"drive/root:/Backups/servers", "/Backups/servers", true},
+		{"/drive/root:", "/", true},
+		{"/drive/root:/", "/", true},
+		{"01ABCDEF2GHIJKLMN", "", false},
+		{"ABC123!456", "", false},
+		{"Backups/servers", "", false},
+		{"", "", false},
+	}
+
+	for _, tt := range tests {
+		path, ok := ParsePath(tt.ref)
+		if path != tt.wantPath || ok != tt.wantOK {
+			t.Errorf("ParsePath(%q) = %q, %v, want %q, %v", tt.ref, path, ok, tt.wantPath, tt.wantOK)
+		}
+	}
+}
+
+func TestGetFolderPath(t *testing.T) {
+	tests := []struct {
+		name   string
+		parent string
+		item   string
+		want   string
+	}{
+		{"drive root", "", "root", "/"},
+		{"top level", "/drive/root:", "Backups", "/Backups"},
+		{"space", "/drive/root:/My%20Backups", "servers", "/My Backups/servers"},
+		{"non-ASCII", "/drive/root:/Sicherungen/B%C3%BCro", "Käse 01", "/Sicherungen/Büro/Käse 01"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body strings.Builder
+			json.NewEncoder(&body).Encode(map[string]interface{}{
+				"id":              "ABC!1",
+				"name":            tt.item,
+				"folder":          map[string]interface{}{},
+				"parentReference": map[string]string{"path": tt.parent},
+			})
+			c := &Client{baseURL: "https://graph.test", httpClient: &http.Client{Transport: roundTripFunc(func(*http.Request) (*http.Response, error) {
+				return &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(body.String()))}, nil
+			})}}
+
+			folder, err := c.GetFolder("ABC!1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if folder.Path != tt.want {
+				t.Errorf("Path = %q, want %q", folder.Path, tt.want)
+			}
+		})
+	}
+}
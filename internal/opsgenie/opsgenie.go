@@ -0,0 +1,117 @@
+package opsgenie
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultBaseURL is the Opsgenie Alert API host
+const DefaultBaseURL = "https://api.opsgenie.com"
+
+// Client represents an Opsgenie Alert API client
+type Client struct {
+	apiKey     string
+	baseURL    string
+	httpClient *http.Client
+}
+
+// alertRequest represents the body of a create alert request
+type alertRequest struct {
+	Message     string            `json:"message"`
+	Alias       string            `json:"alias"`
+	Description string            `json:"description,omitempty"`
+	Source      string            `json:"source"`
+	Priority    string            `json:"priority"`
+	Details     map[string]string `json:"details,omitempty"`
+}
+
+// closeRequest represents the body of a close alert request
+type closeRequest struct {
+	Source string `json:"source"`
+	Note   string `json:"note,omitempty"`
+}
+
+// New creates a new Opsgenie client. An empty baseURL selects DefaultBaseURL.
+func New(apiKey, baseURL string) *Client {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+
+	return &Client{
+		apiKey:     apiKey,
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Name returns the channel name used in logs
+func (c *Client) Name() string {
+	return "opsgenie"
+}
+
+// priorities maps severities to Opsgenie alert priorities
+var priorities = map[string]string{
+	"critical": "P1",
+	"error":    "P2",
+	"warning":  "P3",
+	"info":     "P5",
+}
+
+// Trigger creates an alert; Opsgenie de-duplicates open alerts by alias.
+// severity is one of critical, error, warning or info; empty means critical.
+func (c *Client) Trigger(alias, summary, severity string, details map[string]string) error {
+	priority, ok := priorities[severity]
+	if !ok {
+		priority = "P1"
+	}
+
+	return c.post("/v2/alerts", alertRequest{
+		Message:  summary,
+		Alias:    alias,
+		Source:   "restic-backup-checker",
+		Priority: priority,
+		Details:  details,
+	})
+}
+
+// Resolve closes the open alert with the given alias
+func (c *Client) Resolve(alias string) error {
+	path := fmt.Sprintf("/v2/alerts/%s/close?identifierType=alias", url.PathEscape(alias))
+	return c.post(path, closeRequest{
+		Source: "restic-backup-checker",
+		Note:   "Backup is up to date again",
+	})
+}
+
+// post sends an authenticated JSON request to the Alert API
+func (c *Client) post(path string, body interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", c.baseURL+path, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "GenieKey "+c.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send opsgenie request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted {
+		return fmt.Errorf("opsgenie request failed with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
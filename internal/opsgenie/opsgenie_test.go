@@ -0,0 +1,58 @@
+package opsgenie
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTrigger(t *testing.T) {
+	tests := []struct {
+		severity string
+		want     string
+	}{
+		{"critical", "P1"},
+		{"error", "P2"},
+		{"warning", "P3"},
+		{"info", "P5"},
+		{"", "P1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.severity, func(t *testing.T) {
+			var got alertRequest
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/v2/alerts" || r.Header.Get("Authorization") != "GenieKey api-key" {
+					t.Errorf("request = %s %v", r.URL.Path, r.Header)
+				}
+				json.NewDecoder(r.Body).Decode(&got)
+				w.WriteHeader(http.StatusAccepted)
+			}))
+			defer srv.Close()
+
+			if err := New("api-key", srv.URL).Trigger("key/1", "web01 failed", tt.severity, nil); err != nil {
+				t.Fatal(err)
+			}
+			if got.Alias != "key/1" || got.Priority != tt.want {
+				t.Errorf("alert = %+v, want priority %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	var path, query string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, query = r.URL.EscapedPath(), r.URL.RawQuery
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	if err := New("api-key", srv.URL).Resolve("restic-backup-checker/F1/web01"); err != nil {
+		t.Fatal(err)
+	}
+	if want := "/v2/alerts/restic-backup-checker%2FF1%2Fweb01/close"; path != want || query != "identifierType=alias" {
+		t.Errorf("request = %s?%s, want %s?identifierType=alias", path, query, want)
+	}
+}
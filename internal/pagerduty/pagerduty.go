@@ -0,0 +1,103 @@
+package pagerduty
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultBaseURL is the PagerDuty Events API v2 endpoint host
+const DefaultBaseURL = "https://events.pagerduty.com"
+
+// Client represents a PagerDuty Events API v2 client
+type Client struct {
+	routingKey string
+	baseURL    string
+	httpClient *http.Client
+}
+
+// event represents a PagerDuty Events API v2 request body
+type event struct {
+	RoutingKey  string   `json:"routing_key"`
+	EventAction string   `json:"event_action"`
+	DedupKey    string   `json:"dedup_key"`
+	Payload     *payload `json:"payload,omitempty"`
+}
+
+// payload holds the incident details of a trigger event
+type payload struct {
+	Summary       string            `json:"summary"`
+	Source        string            `json:"source"`
+	Severity      string            `json:"severity"`
+	CustomDetails map[string]string `json:"custom_details,omitempty"`
+}
+
+// New creates a new PagerDuty client. An empty baseURL selects DefaultBaseURL.
+func New(routingKey, baseURL string) *Client {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+
+	return &Client{
+		routingKey: routingKey,
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Name returns the channel name used in logs
+func (c *Client) Name() string {
+	return "pagerduty"
+}
+
+// Trigger opens (or updates) the incident identified by dedupKey. severity is
+// one of critical, error, warning or info; empty means critical.
+func (c *Client) Trigger(dedupKey, summary, severity string, details map[string]string) error {
+	if severity == "" {
+		severity = "critical"
+	}
+
+	return c.send(event{
+		RoutingKey:  c.routingKey,
+		EventAction: "trigger",
+		DedupKey:    dedupKey,
+		Payload: &payload{
+			Summary:       summary,
+			Source:        "restic-backup-checker",
+			Severity:      severity,
+			CustomDetails: details,
+		},
+	})
+}
+
+// Resolve resolves the incident identified by dedupKey
+func (c *Client) Resolve(dedupKey string) error {
+	return c.send(event{
+		RoutingKey:  c.routingKey,
+		EventAction: "resolve",
+		DedupKey:    dedupKey,
+	})
+}
+
+// send posts an event to the enqueue endpoint
+func (c *Client) send(ev event) error {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	resp, err := c.httpClient.Post(c.baseURL+"/v2/enqueue", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to send pagerduty event: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted {
+		return fmt.Errorf("pagerduty %s event failed with status %d", ev.EventAction, resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,60 @@
+package pagerduty
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestEvents(t *testing.T) {
+	tests := []struct {
+		name         string
+		send         func(c *Client) error
+		wantAction   string
+		wantSeverity string
+	}{
+		{"trigger", func(c *Client) error { return c.Trigger("key-1", "web01 failed", "warning", nil) }, "trigger", "warning"},
+		{"trigger without severity", func(c *Client) error { return c.Trigger("key-1", "web01 failed", "", nil) }, "trigger", "critical"},
+		{"resolve", func(c *Client) error { return c.Resolve("key-1") }, "resolve", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got event
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/v2/enqueue" {
+					t.Errorf("path = %s", r.URL.Path)
+				}
+				json.NewDecoder(r.Body).Decode(&got)
+				w.WriteHeader(http.StatusAccepted)
+			}))
+			defer srv.Close()
+
+			if err := tt.send(New("routing", srv.URL+"/")); err != nil {
+				t.Fatal(err)
+			}
+			if got.RoutingKey != "routing" || got.DedupKey != "key-1" || got.EventAction != tt.wantAction {
+				t.Errorf("event = %+v", got)
+			}
+			if tt.wantSeverity == "" {
+				if got.Payload != nil {
+					t.Errorf("resolve event has a payload: %+v", got.Payload)
+				}
+			} else if got.Payload == nil || got.Payload.Severity != tt.wantSeverity {
+				t.Errorf("payload = %+v, want severity %s", got.Payload, tt.wantSeverity)
+			}
+		})
+	}
+}
+
+func TestEventRejected(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	if err := New("routing", srv.URL).Resolve("key-1"); err == nil {
+		t.Error("Resolve() succeeded on status 400")
+	}
+}
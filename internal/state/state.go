@@ -0,0 +1,309 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"restic-backup-checker/internal/templates"
+)
+
+// maxHistory is the number of check results kept per client
+const maxHistory = 30
+
+// maxRuns is the number of check runs kept
+const maxRuns = 100
+
+// Store persists per-client monitoring state between checks and restarts
+type Store struct {
+	Clients map[string]*ClientState `json:"clients"`
+	Queue   []QueuedAlert           `json:"queue,omitempty"`
+	Auth    AuthState               `json:"auth"`
+	Runs    []RunRecord             `json:"runs,omitempty"`
+	path    string
+	mu      sync.Mutex
+}
+
+// ClientState holds what the monitor remembers about a single client
+type ClientState struct {
+	ClientName    string    `json:"client_name"`
+	DisplayName   string    `json:"display_name,omitempty"` // shown instead of ClientName if set
+	MonitorPath   string    `json:"monitor_path"`
+	FolderID      string    `json:"folder_id"`
+	FolderPath    string    `json:"folder_path,omitempty"`
+	HasBackup     bool      `json:"has_backup"`
+	Status        string    `json:"status,omitempty"`
+	FileCount     int       `json:"file_count"`
+	LastBackup    time.Time `json:"last_backup"`
+	LastChecked   time.Time `json:"last_checked"`
+	LastError     string    `json:"last_error,omitempty"`
+	IncidentOpen  bool      `json:"incident_open"`
+	IncidentLevel int       `json:"incident_level"`
+	SilencedUntil time.Time `json:"silenced_until"`
+	AckedBy       string    `json:"acked_by,omitempty"`
+	AckedAt       time.Time `json:"acked_at"`
+
+	ConsecutiveFailures int            `json:"consecutive_failures"`
+	FailingSince        time.Time      `json:"failing_since"`
+	EscalationLevel     int            `json:"escalation_level"`
+	History             []HistoryEntry `json:"history,omitempty"`
+}
+
+// RunRecord is a completed check run
+type RunRecord struct {
+	ID string `json:"id"` // run_id field of the run's log messages
+	templates.Run
+	Error string `json:"error,omitempty"` // why the check could not be performed, if it couldn't
+}
+
+// AuthState tracks OneDrive authentication failures so they are reported once
+type AuthState struct {
+	FailureKind         string    `json:"failure_kind,omitempty"`
+	FailingSince        time.Time `json:"failing_since"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	Notified            bool      `json:"notified"`
+	ReminderSent        time.Time `json:"reminder_sent"`
+}
+
+// QueuedAlert is an alert held back during a route's quiet hours
+type QueuedAlert struct {
+	Route  string           `json:"route"`
+	Queued time.Time        `json:"queued"`
+	Client templates.Client `json:"client"`
+}
+
+// HistoryEntry records the result of one check of a client
+type HistoryEntry struct {
+	Time      time.Time `json:"time"`
+	HasBackup bool      `json:"has_backup"`
+	Status    string    `json:"status,omitempty"`
+	FileCount int       `json:"file_count"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// AddHistory appends a check result, dropping the oldest beyond maxHistory
+func (cs *ClientState) AddHistory(entry HistoryEntry) {
+	cs.History = append(cs.History, entry)
+	if len(cs.History) > maxHistory {
+		cs.History = cs.History[len(cs.History)-maxHistory:]
+	}
+}
+
+// CurrentStatus returns the status of the client, falling back to OK or
+// FAILED for state recorded before statuses were classified
+func (cs *ClientState) CurrentStatus() string {
+	switch {
+	case cs.Status != "":
+		return cs.Status
+	case cs.HasBackup:
+		return templates.StatusOK
+	default:
+		return "FAILED"
+	}
+}
+
+// Name returns the name the client is shown with
+func (cs *ClientState) Name() string {
+	if cs.DisplayName != "" {
+		return cs.DisplayName
+	}
+	return cs.ClientName
+}
+
+// IsAcknowledged reports whether the client's current failure was acknowledged
+func (cs *ClientState) IsAcknowledged() bool {
+	return !cs.AckedAt.IsZero()
+}
+
+// IsSilenced reports whether notifications for the client are silenced at t
+func (cs *ClientState) IsSilenced(t time.Time) bool {
+	return t.Before(cs.SilencedUntil)
+}
+
+// ClientKey returns the stable identifier of a client within a monitored path
+func ClientKey(monitorPath, clientName string) string {
+	return monitorPath + "/" + clientName
+}
+
+// Load loads the state file, returning an empty store if it does not exist yet
+func Load(path string) (*Store, error) {
+	s := &Store{
+		Clients: make(map[string]*ClientState),
+		path:    path,
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return s, fmt.Errorf("failed to read state file: %w", err)
+	}
+
+	if err := json.Unmarshal(data, s); err != nil {
+		return s, fmt.Errorf("failed to unmarshal state: %w", err)
+	}
+	if s.Clients == nil {
+		s.Clients = make(map[string]*ClientState)
+	}
+
+	return s, nil
+}
+
+// Save writes the state file
+func (s *Store) Save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	if err := os.WriteFile(s.path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+
+	return nil
+}
+
+// Client returns the state for a client, creating it if needed
+func (s *Store) Client(monitorPath, clientName string) *ClientState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := ClientKey(monitorPath, clientName)
+	cs, ok := s.Clients[key]
+	if !ok {
+		cs = &ClientState{
+			ClientName:  clientName,
+			MonitorPath: monitorPath,
+		}
+		s.Clients[key] = cs
+	}
+
+	return cs
+}
+
+// Get returns the state of a client, or nil if it has none
+func (s *Store) Get(monitorPath, clientName string) *ClientState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.Clients[ClientKey(monitorPath, clientName)]
+}
+
+// Remove deletes and returns the state of a client, or nil if it has none
+func (s *Store) Remove(monitorPath, clientName string) *ClientState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := ClientKey(monitorPath, clientName)
+	cs := s.Clients[key]
+	delete(s.Clients, key)
+	return cs
+}
+
+// AddRun records a check run, dropping the oldest beyond maxRuns
+func (s *Store) AddRun(run RunRecord) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Runs = append(s.Runs, run)
+	if len(s.Runs) > maxRuns {
+		s.Runs = s.Runs[len(s.Runs)-maxRuns:]
+	}
+}
+
+// Enqueue holds back an alert for a route
+func (s *Store) Enqueue(alert QueuedAlert) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Queue = append(s.Queue, alert)
+}
+
+// Queued returns the alerts queued for a route, oldest first
+func (s *Store) Queued(route string) []QueuedAlert {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var queued []QueuedAlert
+	for _, alert := range s.Queue {
+		if alert.Route == route {
+			queued = append(queued, alert)
+		}
+	}
+	return queued
+}
+
+// Dequeue removes the alerts queued for a route up to and including until
+func (s *Store) Dequeue(route string, until time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var kept []QueuedAlert
+	for _, alert := range s.Queue {
+		if alert.Route != route || alert.Queued.After(until) {
+			kept = append(kept, alert)
+		}
+	}
+	s.Queue = kept
+}
+
+// QueuedRoutes returns the names of the routes with queued alerts
+func (s *Store) QueuedRoutes() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seen := make(map[string]bool)
+	var routes []string
+	for _, alert := range s.Queue {
+		if !seen[alert.Route] {
+			seen[alert.Route] = true
+			routes = append(routes, alert.Route)
+		}
+	}
+	return routes
+}
+
+// List returns all known clients sorted by name
+func (s *Store) List() []*ClientState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	clients := make([]*ClientState, 0, len(s.Clients))
+	for _, cs := range s.Clients {
+		clients = append(clients, cs)
+	}
+
+	sort.Slice(clients, func(i, j int) bool {
+		if clients[i].ClientName != clients[j].ClientName {
+			return clients[i].ClientName < clients[j].ClientName
+		}
+		return clients[i].MonitorPath < clients[j].MonitorPath
+	})
+
+	return clients
+}
+
+// Find returns the clients whose name or shown name matches name
+// case-insensitively
+func (s *Store) Find(name string) []*ClientState {
+	var matches []*ClientState
+	for _, cs := range s.List() {
+		if strings.EqualFold(cs.ClientName, name) || strings.EqualFold(cs.DisplayName, name) {
+			matches = append(matches, cs)
+		}
+	}
+	return matches
+}
@@ -0,0 +1,82 @@
+package telegram
+
+import (
+	"log"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Alert button actions carried in callback data as "<action>:<key>"
+const (
+	ActionAcknowledge = "ack"
+	ActionSnooze      = "snooze"
+	ActionRecheck     = "recheck"
+)
+
+// Callback represents a press of an alert button
+type Callback struct {
+	ChatID    int64
+	MessageID int
+	From      string
+	Action    string
+	Key       string
+}
+
+// CallbackResult tells Listen how to respond to a button press
+type CallbackResult struct {
+	Notice     string // short text shown to the user who pressed the button
+	Note       string // appended to the alert message if non-empty
+	KeepButton bool   // keep the inline keyboard on the edited message
+}
+
+// CallbackHandler handles an alert button press
+type CallbackHandler func(cb Callback) CallbackResult
+
+// alertKeyboard returns the inline keyboard attached to backup alerts
+func alertKeyboard(key string) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Acknowledge", ActionAcknowledge+":"+key),
+			tgbotapi.NewInlineKeyboardButtonData("Snooze 4h", ActionSnooze+":"+key),
+			tgbotapi.NewInlineKeyboardButtonData("Re-check now", ActionRecheck+":"+key),
+		),
+	)
+}
+
+// handleCallback dispatches a callback query, answers it and updates the alert
+func (c *Client) handleCallback(query *tgbotapi.CallbackQuery, handle CallbackHandler) {
+	action, key, ok := strings.Cut(query.Data, ":")
+	if !ok || query.Message == nil {
+		return
+	}
+
+	result := handle(Callback{
+		ChatID:    query.Message.Chat.ID,
+		MessageID: query.Message.MessageID,
+		From:      senderName(query.From),
+		Action:    action,
+		Key:       key,
+	})
+
+	if _, err := c.bot.Request(tgbotapi.NewCallback(query.ID, result.Notice)); err != nil {
+		log.Printf("Failed to answer callback query: %v", err)
+	}
+
+	if result.Note == "" {
+		return
+	}
+
+	// Keep the original formatting by reusing its entities; the note is appended
+	// after them so their offsets stay valid.
+	edit := tgbotapi.NewEditMessageText(query.Message.Chat.ID, query.Message.MessageID,
+		query.Message.Text+"\n\n"+result.Note)
+	edit.Entities = query.Message.Entities
+	if result.KeepButton {
+		edit.ReplyMarkup = query.Message.ReplyMarkup
+	}
+
+	if _, err := c.bot.Send(edit); err != nil {
+		log.Printf("Failed to update alert message: %v", err)
+	}
+}
@@ -0,0 +1,97 @@
+package telegram
+
+import (
+	"log"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Command represents a bot command received from a chat
+type Command struct {
+	ChatID int64
+	From   string
+	Name   string
+	Args   []string
+}
+
+// CommandHandler handles a command and returns the HTML-formatted reply text
+type CommandHandler func(cmd Command) string
+
+// Listen long-polls getUpdates until stop is closed. Commands are accepted
+// from the configured chat and commandChats; alert button presses also from
+// buttonChats. Updates from any other chat are ignored and logged.
+func (c *Client) Listen(stop <-chan struct{}, commandChats, buttonChats []int64, handle CommandHandler, handleCallback CallbackHandler) {
+	allowed := map[int64]bool{c.chatID: true}
+	for _, id := range commandChats {
+		allowed[id] = true
+	}
+	allowedButtons := make(map[int64]bool, len(allowed)+len(buttonChats))
+	for id := range allowed {
+		allowedButtons[id] = true
+	}
+	for _, id := range buttonChats {
+		allowedButtons[id] = true
+	}
+
+	u := tgbotapi.NewUpdate(0)
+	u.Timeout = 60
+	updates := c.bot.GetUpdatesChan(u)
+
+	for {
+		select {
+		case <-stop:
+			c.bot.StopReceivingUpdates()
+			return
+		case update, ok := <-updates:
+			if !ok {
+				return
+			}
+
+			if query := update.CallbackQuery; query != nil && query.Message != nil {
+				if !allowedButtons[query.Message.Chat.ID] {
+					log.Printf("Ignoring button press from unauthorised chat %d (%s)",
+						query.Message.Chat.ID, senderName(query.From))
+					continue
+				}
+				c.handleCallback(query, handleCallback)
+				continue
+			}
+
+			msg := update.Message
+			if msg == nil || !msg.IsCommand() {
+				continue
+			}
+
+			if !allowed[msg.Chat.ID] {
+				log.Printf("Ignoring command /%s from unauthorised chat %d (%s)",
+					msg.Command(), msg.Chat.ID, senderName(msg.From))
+				continue
+			}
+
+			cmd := Command{
+				ChatID: msg.Chat.ID,
+				From:   senderName(msg.From),
+				Name:   msg.Command(),
+				Args:   strings.Fields(msg.CommandArguments()),
+			}
+
+			if reply := handle(cmd); reply != "" {
+				if err := c.SendMessageTo(cmd.ChatID, reply); err != nil {
+					log.Printf("Failed to reply to /%s: %v", cmd.Name, err)
+				}
+			}
+		}
+	}
+}
+
+// senderName returns a human-readable name for a Telegram user
+func senderName(user *tgbotapi.User) string {
+	if user == nil {
+		return "unknown"
+	}
+	if user.UserName != "" {
+		return "@" + user.UserName
+	}
+	return strings.TrimSpace(user.FirstName + " " + user.LastName)
+}
@@ -0,0 +1,189 @@
+package telegram
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxMessageLength is Telegram's limit on the length of a single message
+const maxMessageLength = 4096
+
+// htmlEscaper escapes the characters that Telegram's HTML parse mode reserves
+var htmlEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// htmlTag matches an HTML tag
+var htmlTag = regexp.MustCompile(`<[^>]*>`)
+
+// Escape escapes s for interpolation into an HTML-formatted message
+func Escape(s string) string {
+	return htmlEscaper.Replace(s)
+}
+
+// stripHTML converts an HTML-formatted message to plain text
+func stripHTML(s string) string {
+	return html.UnescapeString(htmlTag.ReplaceAllString(s, ""))
+}
+
+// splitMessage splits an HTML-formatted message into chunks of at most limit
+// UTF-16 code units, Telegram's unit of message length. It breaks at line
+// boundaries where possible and never inside a tag or entity. Tags that span
+// chunks are closed at the end of one chunk and reopened at the start of the
+// next, so every chunk is valid on its own.
+func splitMessage(message string, limit int) []string {
+	if utf16Len(message) <= limit {
+		return []string{message}
+	}
+
+	s := &splitter{limit: limit}
+	for _, line := range strings.SplitAfter(message, "\n") {
+		if !s.fits(line) {
+			s.flush()
+		}
+		if s.fits(line) {
+			s.write(line)
+			continue
+		}
+
+		// A line longer than a message is split between tags, entities and runes
+		for _, token := range htmlTokens(line) {
+			if !s.fits(token) {
+				s.flush()
+			}
+			s.write(token)
+		}
+	}
+	s.flush()
+
+	return s.chunks
+}
+
+// openTag is a tag that has not been closed yet
+type openTag struct {
+	name string // lower-case tag name
+	tag  string // the opening tag with its attributes
+}
+
+// splitter accumulates the chunks of a message being split
+type splitter struct {
+	limit   int
+	chunks  []string
+	current strings.Builder
+	length  int // of current in UTF-16 code units
+	start   int // length of the reopened tags current starts with
+	open    []openTag
+}
+
+// fits reports whether text can be added to the current chunk, leaving room
+// to close the tags open after it
+func (s *splitter) fits(text string) bool {
+	open := applyTags(s.open, text)
+	return s.length+utf16Len(text)+utf16Len(closingTags(open)) <= s.limit
+}
+
+// write adds text to the current chunk
+func (s *splitter) write(text string) {
+	s.current.WriteString(text)
+	s.length += utf16Len(text)
+	s.open = applyTags(s.open, text)
+}
+
+// flush completes the current chunk, if it has any content, and starts the
+// next one by reopening the tags still open
+func (s *splitter) flush() {
+	if s.length == s.start {
+		return
+	}
+	chunk := strings.TrimRight(s.current.String(), "\n") + closingTags(s.open)
+	if strings.TrimSpace(stripHTML(chunk)) != "" {
+		s.chunks = append(s.chunks, chunk)
+	}
+
+	s.current.Reset()
+	for _, t := range s.open {
+		s.current.WriteString(t.tag)
+	}
+	s.length = utf16Len(s.current.String())
+	s.start = s.length
+}
+
+// htmlEntity matches an HTML entity at the start of a string
+var htmlEntity = regexp.MustCompile(`^&(#[0-9]+|#[xX][0-9a-fA-F]+|[A-Za-z]+);`)
+
+// htmlTokens splits HTML text into tags, entities and single runes, the
+// places where it may be broken
+func htmlTokens(text string) []string {
+	var tokens []string
+	for len(text) > 0 {
+		n := 0
+		switch text[0] {
+		case '<':
+			n = strings.IndexByte(text, '>') + 1
+		case '&':
+			n = len(htmlEntity.FindString(text))
+		}
+		if n == 0 {
+			_, n = utf8.DecodeRuneInString(text)
+		}
+		tokens = append(tokens, text[:n])
+		text = text[n:]
+	}
+	return tokens
+}
+
+// applyTags returns the tags open after text, given those open before it
+func applyTags(open []openTag, text string) []openTag {
+	tags := htmlTag.FindAllString(text, -1)
+	if len(tags) == 0 {
+		return open
+	}
+
+	open = append([]openTag(nil), open...)
+	for _, tag := range tags {
+		name := tagName(tag)
+		switch {
+		case strings.HasPrefix(tag, "</"):
+			for i := len(open) - 1; i >= 0; i-- {
+				if open[i].name == name {
+					open = append(open[:i], open[i+1:]...)
+					break
+				}
+			}
+		case !strings.HasSuffix(tag, "/>"):
+			open = append(open, openTag{name: name, tag: tag})
+		}
+	}
+	return open
+}
+
+// closingTags returns the tags closing open, innermost first
+func closingTags(open []openTag) string {
+	var b strings.Builder
+	for i := len(open) - 1; i >= 0; i-- {
+		b.WriteString("</" + open[i].name + ">")
+	}
+	return b.String()
+}
+
+// tagName returns the lower-case name of an opening or closing tag
+func tagName(tag string) string {
+	name := strings.TrimLeft(strings.TrimPrefix(tag, "<"), "/")
+	if i := strings.IndexAny(name, " \t\n/>"); i >= 0 {
+		name = name[:i]
+	}
+	return strings.ToLower(name)
+}
+
+// utf16Len returns the length of s in UTF-16 code units
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		if r >= 0x10000 {
+			n += 2
+		} else {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,115 @@
+package telegram
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplitMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		limit   int
+		want    []string
+	}{
+		{
+			name:    "short message",
+			message: "<b>Backup Status</b>\nall fine",
+			limit:   100,
+			want:    []string{"<b>Backup Status</b>\nall fine"},
+		},
+		{
+			name:    "breaks at lines",
+			message: "first line\nsecond line\nthird line",
+			limit:   24,
+			want:    []string{"first line\nsecond line", "third line"},
+		},
+		{
+			name:    "reopens pre",
+			message: "<pre>\naaaaaa\nbbbbbb\n</pre>",
+			limit:   20,
+			want:    []string{"<pre>\naaaaaa</pre>", "<pre>bbbbbb\n</pre>"},
+		},
+		{
+			name:    "reopens nested tags with attributes",
+			message: `<a href="x"><b>aaaa bbb</b></a>`,
+			limit:   27,
+			want:    []string{`<a href="x"><b>aaaa</b></a>`, `<a href="x"><b> bbb</b></a>`},
+		},
+		{
+			name:    "keeps entities whole",
+			message: "aaa&amp;bbb",
+			limit:   5,
+			want:    []string{"aaa", "&amp;", "bbb"},
+		},
+		{
+			name:    "counts UTF-16 code units",
+			message: "😀😀😀",
+			limit:   4,
+			want:    []string{"😀😀", "😀"},
+		},
+		{
+			name:    "skips chunks without text",
+			message: "aaaa\n\n\n\nbbbb",
+			limit:   5,
+			want:    []string{"aaaa", "bbbb"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := splitMessage(tt.message, tt.limit)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("splitMessage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitMessageChunksAreValid(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("📋 <b>Backup Status</b>\n\n<pre>\n")
+	for i := 0; i < 300; i++ {
+		b.WriteString("client-&lt;name&gt;-😀  OK  2h ago\n")
+	}
+	b.WriteString("</pre>\n<i>" + strings.Repeat("long line ", 1000) + "</i>")
+	message := b.String()
+
+	chunks := splitMessage(message, maxMessageLength)
+	if len(chunks) < 2 {
+		t.Fatalf("expected several chunks, got %d", len(chunks))
+	}
+
+	var text strings.Builder
+	for i, chunk := range chunks {
+		if n := utf16Len(chunk); n > maxMessageLength {
+			t.Errorf("chunk %d is %d UTF-16 code units long", i, n)
+		}
+		if open := applyTags(nil, chunk); len(open) != 0 {
+			t.Errorf("chunk %d leaves %v open", i, open)
+		}
+		text.WriteString(stripHTML(chunk))
+	}
+
+	// Only trailing newlines are dropped at chunk boundaries
+	strip := func(s string) string { return strings.ReplaceAll(s, "\n", "") }
+	if strip(text.String()) != strip(stripHTML(message)) {
+		t.Error("chunks do not add up to the message text")
+	}
+}
+
+func TestTagName(t *testing.T) {
+	tests := map[string]string{
+		"<b>":              "b",
+		"</B>":             "b",
+		`<a href="x">`:     "a",
+		"<pre>":            "pre",
+		"<tg-spoiler>":     "tg-spoiler",
+		`<span class="x">`: "span",
+	}
+	for tag, want := range tests {
+		if got := tagName(tag); got != want {
+			t.Errorf("tagName(%q) = %q, want %q", tag, got, want)
+		}
+	}
+}
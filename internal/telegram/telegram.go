@@ -3,6 +3,7 @@ package telegram
 import (
 	"fmt"
 	"log"
+	"strings"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
@@ -27,16 +28,76 @@ func New(botToken string, chatID int64) *Client {
 	}
 }
 
-// SendMessage sends a message to the configured chat
+// Check verifies a bot token with getMe and returns a client using it
+func Check(botToken string) (*Client, error) {
+	bot, err := tgbotapi.NewBotAPI(botToken)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{bot: bot}, nil
+}
+
+// Username returns the bot's user name
+func (c *Client) Username() string {
+	return c.bot.Self.UserName
+}
+
+// ChatTitle looks up a chat with getChat and returns its title, or the user
+// name for private chats
+func (c *Client) ChatTitle(chatID int64) (string, error) {
+	chat, err := c.bot.GetChat(tgbotapi.ChatInfoConfig{ChatConfig: tgbotapi.ChatConfig{ChatID: chatID}})
+	if err != nil {
+		return "", err
+	}
+	if chat.Title != "" {
+		return chat.Title, nil
+	}
+	return chat.UserName, nil
+}
+
+// SendMessage sends an HTML-formatted message to the configured chat
 func (c *Client) SendMessage(message string) error {
+	return c.SendMessageTo(c.chatID, message)
+}
+
+// SendMessageTo sends an HTML-formatted message to the given chat, splitting
+// it into several messages if it exceeds Telegram's length limit
+func (c *Client) SendMessageTo(chatID int64, message string) error {
+	return c.sendChunks(chatID, message, nil)
+}
+
+// sendChunks sends message in chunks, attaching keyboard to the last one
+func (c *Client) sendChunks(chatID int64, message string, keyboard *tgbotapi.InlineKeyboardMarkup) error {
 	if c.bot == nil {
 		return fmt.Errorf("telegram bot not initialized")
 	}
 
-	msg := tgbotapi.NewMessage(c.chatID, message)
-	msg.ParseMode = tgbotapi.ModeMarkdown
+	chunks := splitMessage(message, maxMessageLength)
+	for i, chunk := range chunks {
+		msg := tgbotapi.NewMessage(chatID, chunk)
+		msg.ParseMode = tgbotapi.ModeHTML
+		if keyboard != nil && i == len(chunks)-1 {
+			msg.ReplyMarkup = *keyboard
+		}
+
+		if err := c.send(msg); err != nil {
+			return err
+		}
+	}
 
+	return nil
+}
+
+// send sends a message, falling back to plain text if Telegram rejects its
+// formatting entities
+func (c *Client) send(msg tgbotapi.MessageConfig) error {
 	_, err := c.bot.Send(msg)
+	if err != nil && strings.Contains(err.Error(), "can't parse entities") {
+		log.Printf("Telegram rejected message formatting, resending as plain text: %v", err)
+		msg.Text = stripHTML(msg.Text)
+		msg.ParseMode = ""
+		_, err = c.bot.Send(msg)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to send telegram message: %w", err)
 	}
@@ -44,57 +105,24 @@ func (c *Client) SendMessage(message string) error {
 	return nil
 }
 
-// SendBackupAlert sends a backup failure alert
-func (c *Client) SendBackupAlert(clientName string, folderPath string, lastBackupTime string) error {
-	message := fmt.Sprintf(
-		"🚨 *Backup Alert*\n\n"+
-			"*Client:* %s\n"+
-			"*Folder:* %s\n"+
-			"*Issue:* No backup found for today\n"+
-			"*Last Backup:* %s\n\n"+
-			"Please check the backup client immediately.",
-		clientName, folderPath, lastBackupTime,
-	)
-
-	return c.SendMessage(message)
-}
+// SendBackupAlert sends a rendered backup failure alert to the given chat. If
+// actionKey is non-empty the alert carries Acknowledge, Snooze and Re-check
+// buttons for that key.
+func (c *Client) SendBackupAlert(chatID int64, message string, actionKey string) error {
+	if actionKey == "" {
+		return c.SendMessageTo(chatID, message)
+	}
 
-// SendBackupSuccess sends a backup success notification
-func (c *Client) SendBackupSuccess(clientName string, folderPath string, fileCount int) error {
-	message := fmt.Sprintf(
-		"✅ *Backup Success*\n\n"+
-			"*Client:* %s\n"+
-			"*Folder:* %s\n"+
-			"*Files:* %d backup files found for today\n\n"+
-			"All backups are up to date.",
-		clientName, folderPath, fileCount,
-	)
-
-	return c.SendMessage(message)
+	keyboard := alertKeyboard(actionKey)
+	return c.sendChunks(chatID, message, &keyboard)
 }
 
-// SendSummaryReport sends a daily summary report
-func (c *Client) SendSummaryReport(totalClients int, successCount int, failedCount int, failedClients []string) error {
-	status := "✅ All Good"
-	if failedCount > 0 {
-		status = "🚨 Issues Found"
-	}
-
-	message := fmt.Sprintf(
-		"📊 *Daily Backup Report*\n\n"+
-			"*Status:* %s\n"+
-			"*Total Clients:* %d\n"+
-			"*Successful:* %d\n"+
-			"*Failed:* %d\n",
-		status, totalClients, successCount, failedCount,
-	)
-
-	if len(failedClients) > 0 {
-		message += "\n*Failed Clients:*\n"
-		for _, client := range failedClients {
-			message += fmt.Sprintf("• %s\n", client)
-		}
-	}
+// SendBackupSuccess sends a rendered backup success notification to the given chat
+func (c *Client) SendBackupSuccess(chatID int64, message string) error {
+	return c.SendMessageTo(chatID, message)
+}
 
-	return c.SendMessage(message)
+// SendSummaryReport sends a rendered summary report to the given chat
+func (c *Client) SendSummaryReport(chatID int64, message string) error {
+	return c.SendMessageTo(chatID, message)
 }
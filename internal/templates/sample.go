@@ -0,0 +1,104 @@
+package templates
+
+import "time"
+
+// Sample returns example data for a notification type covering every field
+// of the data model, used to test-render templates
+func Sample(kind string) interface{} {
+	now := time.Now()
+
+	ok := Client{
+		Name:        "web_server_01",
+		MonitorPath: "01ABCDEF2GHIJKLMNOPQRSTUVWXYZ",
+		MonitorName: "/Backups/Restic",
+		FolderID:    "01ABCDEF3GHIJKLMNOPQRSTUVWXYZ",
+		FolderPath:  "/Backups/Restic/web_server_01",
+		DisplayName: "web_server_01",
+		HasBackup:   true,
+		Status:      StatusOK,
+		LastBackup:  now.Add(-3 * time.Hour),
+		Age:         3 * time.Hour,
+		AgeSeconds:  (3 * time.Hour).Seconds(),
+		FileCount:   2,
+	}
+
+	failed := Client{
+		Name:        "db<primary>",
+		MonitorPath: "01ABCDEF2GHIJKLMNOPQRSTUVWXYZ",
+		MonitorName: "/Backups/Restic",
+		FolderID:    "01ABCDEF4GHIJKLMNOPQRSTUVWXYZ",
+		FolderPath:  "/Backups/Restic/db<primary>",
+		DisplayName: "db<primary>",
+		Status:      StatusStale,
+		LastBackup:  now.Add(-50 * time.Hour),
+		Age:         50 * time.Hour,
+		AgeSeconds:  (50 * time.Hour).Seconds(),
+
+		ConsecutiveFailures: 26,
+		FailingSince:        now.Add(-26 * time.Hour),
+		EscalationLevel:     2,
+		Severity:            "critical",
+	}
+
+	broken := Client{
+		Name:        "nas_&_media",
+		MonitorPath: "01ABCDEF2GHIJKLMNOPQRSTUVWXYZ",
+		MonitorName: "/Backups/Restic",
+		FolderID:    "01ABCDEF5GHIJKLMNOPQRSTUVWXYZ",
+		FolderPath:  "/Backups/Restic/nas_&_media",
+		DisplayName: "nas_&_media",
+		Status:      StatusNotARepo,
+		Error:       "snapshots folder not found in folder 01ABCDEF5GHIJKLMNOPQRSTUVWXYZ. Available subfolders: [data keys]",
+
+		ConsecutiveFailures: 1,
+		FailingSince:        now,
+		Severity:            "error",
+	}
+
+	switch kind {
+	case TypeSuccess:
+		return AlertData{Client: ok, Now: now}
+	case TypeSummary:
+		return SummaryData{
+			Run: Run{
+				Started:    now.Add(-12 * time.Second),
+				Duration:   12 * time.Second,
+				Total:      3,
+				Successful: 1,
+				Failed:     2,
+				Stale:      1,
+				NotARepo:   1,
+			},
+			Clients: []Client{ok, failed, broken},
+			Failed:  []Client{failed, broken},
+			Now:     now,
+		}
+	case TypeAuth:
+		return AuthData{
+			Event:       AuthFailure,
+			Kind:        "invalid_grant",
+			NeedsLogin:  true,
+			Error:       "invalid_grant - AADSTS700082: The refresh token has expired due to inactivity.",
+			Since:       now.Add(-2 * time.Hour),
+			Failures:    2,
+			TokenExpiry: now.Add(-time.Hour),
+			Now:         now,
+		}
+	case TypeFolder:
+		return FolderData{
+			Event:   FolderMoved,
+			ID:      "01ABCDEF2GHIJKLMNOPQRSTUVWXYZ",
+			OldPath: "/Backups/Restic",
+			Path:    "/Archive/Restic",
+			Now:     now,
+		}
+	case TypeDigest:
+		return DigestData{
+			Alerts: []Client{failed, broken},
+			Since:  now.Add(-7 * time.Hour),
+			Now:    now,
+		}
+	default:
+		return AlertData{Client: failed, Now: now}
+	}
+}
@@ -0,0 +1,325 @@
+// Package templates renders notification messages from text/template
+// templates. Every notification type has a built-in default per channel,
+// which can be replaced by a user-provided template file.
+//
+// Alert, success and incident templates are executed with AlertData;
+// summary templates with SummaryData; digest templates with DigestData;
+// auth templates with AuthData. The first line of an email template
+// may be a "Subject:" header. Besides the text/template builtins,
+// templates can use:
+//
+//	esc        escape a value for the channel's markup (HTML for telegram)
+//	formatTime format a time as "2006-01-02 15:04:05", or "Unknown" if unset
+//	formatAge  format a duration as e.g. "2d4h" or "3h12m"
+//	describe   describe a client status, e.g. "No backup in the last 24 hours"
+package templates
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"restic-backup-checker/internal/telegram"
+)
+
+//go:embed defaults/*.tmpl
+var defaultFS embed.FS
+
+// Notification types
+const (
+	TypeAlert    = "alert"
+	TypeSuccess  = "success"
+	TypeSummary  = "summary"
+	TypeIncident = "incident"
+	TypeDigest   = "digest"
+	TypeAuth     = "auth"
+	TypeFolder   = "folder"
+)
+
+// Auth events
+const (
+	AuthFailure   = "failure"   // monitoring is blind until login is run again
+	AuthRecovered = "recovered" // authentication works again
+	AuthExpiring  = "expiring"  // the refresh token is about to expire
+)
+
+// Folder events
+const (
+	FolderMoved   = "moved"   // a monitored folder was moved or renamed
+	FolderDeleted = "deleted" // a monitored folder was deleted or is no longer accessible
+)
+
+// Client statuses
+const (
+	StatusOK          = "OK"           // a snapshot was created in the last 24 hours
+	StatusStale       = "STALE"        // snapshots exist, but none from the last 24 hours
+	StatusNoSnapshots = "NO_SNAPSHOTS" // the snapshots folder is empty
+	StatusNotARepo    = "NOT_A_REPO"   // the folder has no snapshots folder
+	StatusCheckError  = "CHECK_ERROR"  // the folder could not be read
+	StatusAuthError   = "AUTH_ERROR"   // access to the folder was denied
+)
+
+// statusDescriptions holds the human-readable wording of each status
+var statusDescriptions = map[string]string{
+	StatusOK:          "Backup found in the last 24 hours",
+	StatusStale:       "No backup in the last 24 hours",
+	StatusNoSnapshots: "Repository has no snapshots",
+	StatusNotARepo:    "Folder is not a restic repository",
+	StatusCheckError:  "Backup could not be checked",
+	StatusAuthError:   "Access to the backup folder was denied",
+}
+
+// Describe returns the human-readable wording of a client status
+func Describe(status string) string {
+	if d, ok := statusDescriptions[status]; ok {
+		return d
+	}
+	return status
+}
+
+// Client describes a monitored client
+type Client struct {
+	Name        string        `json:"name"`         // client folder name
+	MonitorPath string        `json:"monitor_path"` // ID of the monitored folder the client lives in
+	MonitorName string        `json:"monitor_name"` // path of the monitored folder, or its ID if unknown
+	FolderID    string        `json:"folder_id"`    // drive item ID of the client folder
+	FolderPath  string        `json:"folder_path"`  // full path of the client folder, or its ID if unknown
+	DisplayName string        `json:"display_name"` // human-readable name of the client folder
+	HasBackup   bool          `json:"has_backup"`   // a snapshot was created in the last 24 hours
+	LastBackup  time.Time     `json:"last_backup"`  // creation time of the newest snapshot, zero if none
+	Age         time.Duration `json:"-"`            // time since LastBackup
+	AgeSeconds  float64       `json:"age_seconds"`  // Age in seconds, for JSON
+	FileCount   int           `json:"file_count"`   // snapshots created in the last 24 hours
+	Status      string        `json:"status"`       // OK, STALE, NO_SNAPSHOTS, NOT_A_REPO, CHECK_ERROR or AUTH_ERROR
+	Error       string        `json:"error"`        // error encountered while checking, if any
+
+	ConsecutiveFailures int       `json:"consecutive_failures"` // failed checks in a row
+	FailingSince        time.Time `json:"failing_since"`        // first failed check of the current failure, zero if OK
+	EscalationLevel     int       `json:"escalation_level"`     // reached escalation steps, 0 if not escalated
+	Severity            string    `json:"severity"`             // info, warning, error or critical
+}
+
+// Run describes a completed check run
+type Run struct {
+	Started    time.Time     `json:"started"`
+	Duration   time.Duration `json:"duration"`
+	Total      int           `json:"total"`
+	Successful int           `json:"successful"`
+	Failed     int           `json:"failed"` // all clients not OK
+
+	Stale       int `json:"stale"`
+	NoSnapshots int `json:"no_snapshots"`
+	NotARepo    int `json:"not_a_repo"`
+	CheckErrors int `json:"check_errors"`
+	AuthErrors  int `json:"auth_errors"`
+}
+
+// Count adds a client with the given status to the run's counters
+func (r *Run) Count(status string) {
+	r.Total++
+	if status == StatusOK {
+		r.Successful++
+		return
+	}
+	r.Failed++
+
+	switch status {
+	case StatusStale:
+		r.Stale++
+	case StatusNoSnapshots:
+		r.NoSnapshots++
+	case StatusNotARepo:
+		r.NotARepo++
+	case StatusAuthError:
+		r.AuthErrors++
+	default:
+		r.CheckErrors++
+	}
+}
+
+// AlertData is passed to alert, success and incident templates
+type AlertData struct {
+	Client Client    `json:"client"`
+	Now    time.Time `json:"now"`
+}
+
+// SummaryData is passed to summary templates
+type SummaryData struct {
+	Run     Run       `json:"run"`
+	Clients []Client  `json:"clients"` // all clients
+	Failed  []Client  `json:"failed"`  // clients not OK
+	Now     time.Time `json:"now"`
+}
+
+// SplitSubject splits a rendered email into its subject and body. If the
+// first line is not a "Subject:" header, fallback is used as the subject.
+func SplitSubject(rendered, fallback string) (subject, body string) {
+	first, rest, _ := strings.Cut(rendered, "\n")
+	if s, ok := strings.CutPrefix(first, "Subject:"); ok {
+		return strings.TrimSpace(s), strings.TrimLeft(rest, "\n")
+	}
+	return fallback, rendered
+}
+
+// DigestData is passed to digest templates, which batch the alerts held back
+// during a route's quiet hours
+type DigestData struct {
+	Alerts []Client  `json:"alerts"` // latest alert per client, oldest first
+	Since  time.Time `json:"since"`  // when the first alert was held back
+	Now    time.Time `json:"now"`
+}
+
+// AuthData is passed to auth templates, which report that the checker cannot
+// access OneDrive or soon won't be able to
+type AuthData struct {
+	Event       string    `json:"event"`        // failure, recovered or expiring
+	Kind        string    `json:"kind"`         // invalid_grant, consent_required, network or unknown
+	NeedsLogin  bool      `json:"needs_login"`  // login must be run again to recover
+	Error       string    `json:"error"`        // error of the last failed attempt
+	Since       time.Time `json:"since"`        // first failed attempt
+	Failures    int       `json:"failures"`     // failed attempts in a row
+	TokenExpiry time.Time `json:"token_expiry"` // expected refresh token expiry, zero if unknown
+	Now         time.Time `json:"now"`
+}
+
+// FolderData is passed to folder templates, which report that a monitored
+// folder was moved, renamed or deleted
+type FolderData struct {
+	Event   string    `json:"event"`    // moved or deleted
+	ID      string    `json:"id"`       // drive item ID of the monitored folder
+	OldPath string    `json:"old_path"` // last known path
+	Path    string    `json:"path"`     // new path, empty if deleted
+	Now     time.Time `json:"now"`
+}
+
+// Renderer renders notifications for all types and channels
+type Renderer struct {
+	templates map[string]*template.Template
+}
+
+// New creates a Renderer from the built-in defaults, replacing those named in
+// files. files maps "<type>.<channel>" (e.g. "alert.telegram") to a template file.
+func New(files map[string]string) (*Renderer, error) {
+	r := &Renderer{templates: make(map[string]*template.Template)}
+
+	entries, err := defaultFS.ReadDir("defaults")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read default templates: %w", err)
+	}
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), ".tmpl")
+		text, err := defaultFS.ReadFile("defaults/" + entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read default template %s: %w", name, err)
+		}
+		if err := r.add(name, string(text)); err != nil {
+			return nil, err
+		}
+	}
+
+	for name, path := range files {
+		if _, ok := r.templates[name]; !ok {
+			return nil, fmt.Errorf("unknown template %s (available: %s)", name, strings.Join(r.Names(), ", "))
+		}
+		text, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read template %s: %w", name, err)
+		}
+		if err := r.add(name, string(text)); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+// Default creates a Renderer using only the built-in templates
+func Default() *Renderer {
+	r, err := New(nil)
+	if err != nil {
+		panic(err) // the embedded defaults are always valid
+	}
+	return r
+}
+
+// Names returns the names of all templates, sorted
+func (r *Renderer) Names() []string {
+	names := make([]string, 0, len(r.templates))
+	for name := range r.templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Render renders the template for a notification type and channel
+func (r *Renderer) Render(kind, channel string, data interface{}) (string, error) {
+	name := kind + "." + channel
+	tmpl, ok := r.templates[name]
+	if !ok {
+		return "", fmt.Errorf("no template for %s", name)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render template %s: %w", name, err)
+	}
+
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// add parses a template and registers it under name
+func (r *Renderer) add(name, text string) error {
+	channel := name[strings.LastIndex(name, ".")+1:]
+	tmpl, err := template.New(name).Funcs(funcs(channel)).Parse(text)
+	if err != nil {
+		return fmt.Errorf("failed to parse template %s: %w", name, err)
+	}
+	r.templates[name] = tmpl
+	return nil
+}
+
+// funcs returns the template functions for a channel
+func funcs(channel string) template.FuncMap {
+	esc := func(s string) string { return s }
+	if channel == "telegram" {
+		esc = telegram.Escape
+	}
+
+	return template.FuncMap{
+		"esc":        esc,
+		"formatTime": FormatTime,
+		"formatAge":  FormatAge,
+		"describe":   Describe,
+	}
+}
+
+// FormatTime formats a timestamp for display, or "Unknown" if unset
+func FormatTime(t time.Time) string {
+	if t.IsZero() {
+		return "Unknown"
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
+
+// FormatAge formats a duration compactly, e.g. "2d4h", "3h12m" or "5m"
+func FormatAge(d time.Duration) string {
+	d = d.Round(time.Minute)
+	days := int(d / (24 * time.Hour))
+	hours := int(d % (24 * time.Hour) / time.Hour)
+	minutes := int(d % time.Hour / time.Minute)
+
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd%dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh%dm", hours, minutes)
+	default:
+		return fmt.Sprintf("%dm", minutes)
+	}
+}
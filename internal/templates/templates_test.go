@@ -0,0 +1,122 @@
+package templates
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDefaultTemplatesRenderSamples(t *testing.T) {
+	r := Default()
+	for _, name := range r.Names() {
+		t.Run(name, func(t *testing.T) {
+			kind, channel, _ := strings.Cut(name, ".")
+			out, err := r.Render(kind, channel, Sample(kind))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out == "" {
+				t.Error("rendered nothing")
+			}
+			if channel == "telegram" && strings.Contains(out, "db<primary>") {
+				t.Errorf("client name not escaped for Telegram:\n%s", out)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, text string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(text), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	custom := write("custom.tmpl", "{{esc .Client.Name}} is {{.Client.Status}}")
+	broken := write("broken.tmpl", "{{if .Client.Name}")
+
+	tests := []struct {
+		name    string
+		files   map[string]string
+		want    string // rendered alert.telegram for the sample
+		wantErr string
+	}{
+		{"built-in", nil, "Backup Alert", ""},
+		{"custom", map[string]string{"alert.telegram": custom}, "db&lt;primary&gt; is STALE", ""},
+		{"unknown name", map[string]string{"alert.sms": custom}, "", "unknown template alert.sms"},
+		{"missing file", map[string]string{"alert.telegram": filepath.Join(dir, "missing.tmpl")}, "", "failed to read template"},
+		{"parse error", map[string]string{"alert.telegram": broken}, "", "failed to parse template"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := New(tt.files)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("New() error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			out, err := r.Render("alert", "telegram", Sample("alert"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(out, tt.want) {
+				t.Errorf("Render() = %q, want it to contain %q", out, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderUnknownTemplate(t *testing.T) {
+	if _, err := Default().Render("alert", "sms", Sample("alert")); err == nil {
+		t.Error("Render() succeeded for a template that does not exist")
+	}
+}
+
+func TestSplitSubject(t *testing.T) {
+	tests := []struct {
+		name        string
+		rendered    string
+		wantSubject string
+		wantBody    string
+	}{
+		{"subject header", "Subject: Backup alert: web01\n\nBody text", "Backup alert: web01", "Body text"},
+		{"no header", "Body text\nmore", "fallback", "Body text\nmore"},
+		{"subject only", "Subject: Only", "Only", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subject, body := SplitSubject(tt.rendered, "fallback")
+			if subject != tt.wantSubject || body != tt.wantBody {
+				t.Errorf("SplitSubject() = %q, %q, want %q, %q", subject, body, tt.wantSubject, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestFormatAge(t *testing.T) {
+	tests := []struct {
+		age  time.Duration
+		want string
+	}{
+		{30 * time.Second, "1m"},
+		{5 * time.Minute, "5m"},
+		{3*time.Hour + 12*time.Minute, "3h12m"},
+		{52 * time.Hour, "2d4h"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatAge(tt.age); got != tt.want {
+			t.Errorf("FormatAge(%v) = %q, want %q", tt.age, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+// Sorts the client table when a sortable header is clicked
+(function () {
+  var table = document.getElementById("clients");
+  if (!table) {
+    return;
+  }
+
+  var headers = table.querySelectorAll("th[data-sort]");
+  headers.forEach(function (th) {
+    th.addEventListener("click", function () {
+      var column = Array.prototype.indexOf.call(th.parentNode.children, th);
+      var ascending = !th.classList.contains("sorted-asc");
+      var numeric = th.dataset.sort === "number";
+
+      headers.forEach(function (h) {
+        h.classList.remove("sorted-asc", "sorted-desc");
+      });
+      th.classList.add(ascending ? "sorted-asc" : "sorted-desc");
+
+      var body = table.tBodies[0];
+      var rows = Array.prototype.slice.call(body.rows);
+      rows.sort(function (a, b) {
+        var x = cellValue(a.cells[column], numeric);
+        var y = cellValue(b.cells[column], numeric);
+        var order = numeric ? x - y : x.localeCompare(y);
+        return ascending ? order : -order;
+      });
+      rows.forEach(function (row) {
+        body.appendChild(row);
+      });
+    });
+  });
+
+  function cellValue(cell, numeric) {
+    var value = cell.dataset.value !== undefined ? cell.dataset.value : cell.textContent.trim();
+    return numeric ? parseFloat(value) || 0 : value.toLowerCase();
+  }
+})();
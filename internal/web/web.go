@@ -0,0 +1,306 @@
+// Package web serves a read-only status dashboard and JSON API
+package web
+
+import (
+	"context"
+	"crypto/subtle"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"restic-backup-checker/internal/config"
+	"restic-backup-checker/internal/logger"
+	"restic-backup-checker/internal/state"
+	"restic-backup-checker/internal/templates"
+)
+
+//go:embed assets
+var assets embed.FS
+
+// Source provides the monitoring state shown by the server
+type Source interface {
+	Clients() []state.ClientState // all clients, sorted by name
+	Runs() []state.RunRecord      // recorded check runs, oldest first
+}
+
+// Server serves the dashboard and the JSON API
+type Server struct {
+	config    config.WebConfig
+	source    Source
+	metrics   http.Handler
+	dashboard *template.Template
+}
+
+// Client is a client as returned by the API
+type Client struct {
+	state.ClientState
+	Status       string        `json:"status"`
+	Age          time.Duration `json:"-"`
+	AgeSeconds   float64       `json:"age_seconds,omitempty"` // time since the newest snapshot
+	Silenced     bool          `json:"silenced"`
+	Acknowledged bool          `json:"acknowledged"`
+}
+
+// Status is the response of /api/v1/status
+type Status struct {
+	Generated time.Time        `json:"generated"`
+	LastRun   *state.RunRecord `json:"last_run"`
+	Counts    map[string]int   `json:"counts"` // clients per status
+	Clients   []Client         `json:"clients"`
+}
+
+// New creates a server; metrics, if not nil, is additionally served on /metrics
+func New(cfg config.WebConfig, source Source, metrics http.Handler) (*Server, error) {
+	dashboard, err := template.New("dashboard.html").Funcs(template.FuncMap{
+		"formatTime": templates.FormatTime,
+		"formatAge":  templates.FormatAge,
+		"describe":   templates.Describe,
+		"lower":      strings.ToLower,
+		"sparkline":  sparkline,
+	}).ParseFS(assets, "assets/dashboard.html")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse dashboard template: %w", err)
+	}
+
+	return &Server{
+		config:    cfg,
+		source:    source,
+		metrics:   metrics,
+		dashboard: dashboard,
+	}, nil
+}
+
+// Handler returns the HTTP handler serving all endpoints
+func (s *Server) Handler() http.Handler {
+	static, _ := fs.Sub(assets, "assets")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.handleDashboard)
+	mux.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.FS(static))))
+	mux.HandleFunc("/api/v1/status", s.handleStatus)
+	mux.HandleFunc("/api/v1/clients/", s.handleClient)
+	mux.HandleFunc("/api/v1/runs", s.handleRuns)
+	if s.metrics != nil {
+		mux.Handle("/metrics", s.metrics)
+	}
+
+	return s.authenticate(mux)
+}
+
+// Serve listens on the configured address until stop is closed
+func (s *Server) Serve(stop <-chan struct{}) error {
+	server := &http.Server{Addr: s.config.Listen, Handler: s.Handler(), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-stop
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(ctx)
+	}()
+
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("failed to serve dashboard: %w", err)
+	}
+	return nil
+}
+
+// authenticate rejects requests without the configured credentials
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.authorized(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if s.config.Username != "" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="restic-backup-checker"`)
+		} else {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="restic-backup-checker"`)
+		}
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+	})
+}
+
+// authorized reports whether a request carries valid basic auth credentials
+// or bearer token, or none are required
+func (s *Server) authorized(r *http.Request) bool {
+	if s.config.Username == "" && s.config.BearerToken == "" {
+		return true
+	}
+
+	if s.config.BearerToken != "" {
+		if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok && equal(token, s.config.BearerToken) {
+			return true
+		}
+	}
+
+	if s.config.Username != "" {
+		if user, pass, ok := r.BasicAuth(); ok && equal(user, s.config.Username) && equal(pass, s.config.Password) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// equal compares secrets in constant time
+func equal(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// handleDashboard renders the HTML dashboard
+func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := s.dashboard.Execute(w, s.status()); err != nil {
+		logger.Error("Failed to render dashboard: %v", err)
+	}
+}
+
+// handleStatus returns all clients and the last check run
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.status())
+}
+
+// handleClient returns the clients with the requested name, which may live
+// in several monitored paths
+func (s *Server) handleClient(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/api/v1/clients/")
+	if name == "" {
+		writeError(w, http.StatusNotFound, "client name required")
+		return
+	}
+
+	now := time.Now()
+	var matches []Client
+	for _, cs := range s.source.Clients() {
+		if strings.EqualFold(cs.ClientName, name) || strings.EqualFold(cs.DisplayName, name) {
+			matches = append(matches, newClient(cs, now))
+		}
+	}
+	if len(matches) == 0 {
+		writeError(w, http.StatusNotFound, "unknown client: "+name)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"clients": matches})
+}
+
+// handleRuns returns the recorded check runs, newest first, optionally
+// limited by the "limit" query parameter
+func (s *Server) handleRuns(w http.ResponseWriter, r *http.Request) {
+	runs := s.source.Runs()
+	sort.SliceStable(runs, func(i, j int) bool { return runs[i].Started.After(runs[j].Started) })
+
+	if limit := r.URL.Query().Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, "invalid limit: "+limit)
+			return
+		}
+		if n < len(runs) {
+			runs = runs[:n]
+		}
+	}
+
+	if runs == nil {
+		runs = []state.RunRecord{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"runs": runs})
+}
+
+// status collects the data shown by the dashboard and /api/v1/status
+func (s *Server) status() Status {
+	now := time.Now()
+	status := Status{
+		Generated: now,
+		Counts:    make(map[string]int),
+		Clients:   []Client{},
+	}
+
+	for _, cs := range s.source.Clients() {
+		client := newClient(cs, now)
+		status.Clients = append(status.Clients, client)
+		status.Counts[client.Status]++
+	}
+
+	if runs := s.source.Runs(); len(runs) > 0 {
+		status.LastRun = &runs[len(runs)-1]
+	}
+
+	return status
+}
+
+// newClient converts a client's state for the API
+func newClient(cs state.ClientState, now time.Time) Client {
+	client := Client{
+		ClientState:  cs,
+		Status:       cs.CurrentStatus(),
+		Silenced:     cs.IsSilenced(now),
+		Acknowledged: cs.IsAcknowledged(),
+	}
+	if !cs.LastBackup.IsZero() {
+		client.Age = now.Sub(cs.LastBackup)
+		client.AgeSeconds = client.Age.Seconds()
+	}
+	return client
+}
+
+// sparkline draws a client's check history as an inline SVG bar chart, one
+// bar per check scaled by the number of recent snapshots
+func sparkline(history []state.HistoryEntry) template.HTML {
+	if len(history) == 0 {
+		return ""
+	}
+
+	const barWidth, gap, height = 4, 1, 20
+	max := 1
+	for _, entry := range history {
+		if entry.FileCount > max {
+			max = entry.FileCount
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg class="sparkline" width="%d" height="%d" aria-hidden="true">`, len(history)*(barWidth+gap), height)
+	for i, entry := range history {
+		h := 3 + (height-3)*entry.FileCount/max
+		class := "ok"
+		if !entry.HasBackup {
+			class = "failed"
+			h = height
+		}
+		fmt.Fprintf(&b, `<rect class="%s" x="%d" y="%d" width="%d" height="%d"><title>%s</title></rect>`,
+			class, i*(barWidth+gap), height-h, barWidth, h, template.HTMLEscapeString(templates.FormatTime(entry.Time)))
+	}
+	b.WriteString(`</svg>`)
+
+	return template.HTML(b.String())
+}
+
+// writeJSON writes v as an indented JSON response
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		logger.Error("Failed to write API response: %v", err)
+	}
+}
+
+// writeError writes a JSON error response
+func writeError(w http.ResponseWriter, code int, message string) {
+	writeJSON(w, code, map[string]string{"error": message})
+}
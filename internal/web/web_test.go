@@ -0,0 +1,167 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"restic-backup-checker/internal/config"
+	"restic-backup-checker/internal/state"
+	"restic-backup-checker/internal/templates"
+)
+
+// fakeSource serves fixed clients and runs
+type fakeSource struct {
+	clients []state.ClientState
+	runs    []state.RunRecord
+}
+
+func (f *fakeSource) Clients() []state.ClientState { return f.clients }
+func (f *fakeSource) Runs() []state.RunRecord      { return append([]state.RunRecord(nil), f.runs...) }
+
+// testServer returns the handler of a server with two clients named web01
+// and two runs
+func testServer(t *testing.T, cfg config.WebConfig, metrics http.Handler) http.Handler {
+	t.Helper()
+	now := time.Now()
+	source := &fakeSource{
+		clients: []state.ClientState{
+			{ClientName: "db01", MonitorPath: "F1", Status: templates.StatusStale, LastBackup: now.Add(-30 * time.Hour), SilencedUntil: now.Add(time.Hour)},
+			{ClientName: "web01", MonitorPath: "F1", HasBackup: true, LastBackup: now.Add(-time.Hour)},
+			{ClientName: "web01", MonitorPath: "F2", Status: templates.StatusCheckError, AckedBy: "alice", AckedAt: now},
+		},
+		runs: []state.RunRecord{
+			{ID: "first", Run: templates.Run{Started: now.Add(-2 * time.Hour), Total: 3}},
+			{ID: "second", Run: templates.Run{Started: now.Add(-time.Hour), Total: 3}},
+		},
+	}
+	s, err := New(cfg, source, metrics)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s.Handler()
+}
+
+// get performs a request and returns the recorded response
+func get(h http.Handler, path string, setup func(r *http.Request)) *httptest.ResponseRecorder {
+	r := httptest.NewRequest("GET", path, nil)
+	if setup != nil {
+		setup(r)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	return rec
+}
+
+func TestEndpoints(t *testing.T) {
+	metrics := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("metric 1\n")) })
+	h := testServer(t, config.WebConfig{}, metrics)
+
+	tests := []struct {
+		path     string
+		wantCode int
+		want     string
+	}{
+		{"/", http.StatusOK, "web01"},
+		{"/nope", http.StatusNotFound, ""},
+		{"/api/v1/status", http.StatusOK, `"STALE": 1`},
+		{"/api/v1/clients/WEB01", http.StatusOK, `"monitor_path": "F2"`},
+		{"/api/v1/clients/mail01", http.StatusNotFound, "unknown client: mail01"},
+		{"/api/v1/clients/", http.StatusNotFound, "client name required"},
+		{"/api/v1/runs?limit=1", http.StatusOK, `"id": "second"`},
+		{"/api/v1/runs?limit=-1", http.StatusBadRequest, "invalid limit"},
+		{"/metrics", http.StatusOK, "metric 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := get(h, tt.path, nil)
+			if rec.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantCode)
+			}
+			if !strings.Contains(rec.Body.String(), tt.want) {
+				t.Errorf("body does not contain %q:\n%s", tt.want, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestStatus(t *testing.T) {
+	rec := get(testServer(t, config.WebConfig{}, nil), "/api/v1/status", nil)
+	var status Status
+	if err := json.NewDecoder(rec.Body).Decode(&status); err != nil {
+		t.Fatal(err)
+	}
+
+	if status.LastRun == nil || status.LastRun.ID != "second" {
+		t.Errorf("LastRun = %+v, want the second run", status.LastRun)
+	}
+	if len(status.Clients) != 3 {
+		t.Fatalf("got %d clients, want 3", len(status.Clients))
+	}
+	db, web, failed := status.Clients[0], status.Clients[1], status.Clients[2]
+	if !db.Silenced || db.Acknowledged || db.AgeSeconds < 30*3600 {
+		t.Errorf("db01 = %+v", db)
+	}
+	if web.Status != templates.StatusOK || web.Silenced {
+		t.Errorf("web01 = %+v", web)
+	}
+	if !failed.Acknowledged || failed.AgeSeconds != 0 {
+		t.Errorf("failed web01 = %+v", failed)
+	}
+}
+
+func TestRunsNewestFirst(t *testing.T) {
+	rec := get(testServer(t, config.WebConfig{}, nil), "/api/v1/runs", nil)
+	var resp struct {
+		Runs []state.RunRecord `json:"runs"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Runs) != 2 || resp.Runs[0].ID != "second" {
+		t.Errorf("runs = %+v, want newest first", resp.Runs)
+	}
+}
+
+func TestAuthentication(t *testing.T) {
+	basic := func(user, pass string) func(r *http.Request) {
+		return func(r *http.Request) { r.SetBasicAuth(user, pass) }
+	}
+	bearer := func(token string) func(r *http.Request) {
+		return func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+token) }
+	}
+	both := config.WebConfig{Username: "admin", Password: "secret", BearerToken: "token"}
+
+	tests := []struct {
+		name       string
+		cfg        config.WebConfig
+		setup      func(r *http.Request)
+		wantCode   int
+		wantScheme string // of the WWW-Authenticate header
+	}{
+		{"no auth configured", config.WebConfig{}, nil, http.StatusOK, ""},
+		{"basic auth", both, basic("admin", "secret"), http.StatusOK, ""},
+		{"wrong password", both, basic("admin", "wrong"), http.StatusUnauthorized, "Basic"},
+		{"bearer token", both, bearer("token"), http.StatusOK, ""},
+		{"wrong token", both, bearer("other"), http.StatusUnauthorized, "Basic"},
+		{"no credentials", both, nil, http.StatusUnauthorized, "Basic"},
+		{"token only", config.WebConfig{BearerToken: "token"}, nil, http.StatusUnauthorized, "Bearer"},
+		{"basic auth without username configured", config.WebConfig{BearerToken: "token"}, basic("", "token"), http.StatusUnauthorized, "Bearer"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := get(testServer(t, tt.cfg, nil), "/api/v1/status", tt.setup)
+			if rec.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantCode)
+			}
+			if got := rec.Header().Get("WWW-Authenticate"); !strings.HasPrefix(got, tt.wantScheme) || (tt.wantScheme == "") != (got == "") {
+				t.Errorf("WWW-Authenticate = %q, want scheme %q", got, tt.wantScheme)
+			}
+		})
+	}
+}
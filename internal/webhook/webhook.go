@@ -0,0 +1,53 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Client posts notifications as JSON to webhook URLs
+type Client struct {
+	httpClient *http.Client
+}
+
+// Payload is the JSON body posted to a webhook
+type Payload struct {
+	Type string      `json:"type"` // notification type, e.g. "alert" or "summary"
+	Text string      `json:"text"` // rendered message
+	Data interface{} `json:"data"` // template data the message was rendered from
+}
+
+// New creates a new webhook client
+func New() *Client {
+	return &Client{
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Name returns the channel name used in logs
+func (c *Client) Name() string {
+	return "webhook"
+}
+
+// Send posts payload to url and expects a 2xx response
+func (c *Client) Send(url string, payload Payload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook payload: %w", err)
+	}
+
+	resp, err := c.httpClient.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to send webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook request failed with status %d", resp.StatusCode)
+	}
+
+	return nil
+}